@@ -0,0 +1,100 @@
+package jj
+
+import "strings"
+
+// Prepend inserts a value at the start of the array at the path, like Set
+// with a "[+0]" last path component.
+//
+//	jj.Prepend(`{"friends":["Andy","Carol"]}`, "friends", "Sara")
+//	// {"friends":["Sara","Andy","Carol"]}
+func Prepend(json, path string, value interface{}, options ...SetOptions) (string, error) {
+	var opts SetOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.RawPath {
+		// the raw key becomes the escaped first component of the path
+		path, opts.RawPath = escapeComp(path), false
+		if strings.HasPrefix(path, ":") {
+			path = "\\" + path
+		}
+	}
+	return Set(json, path+".[+0]", value, opts)
+}
+
+// Swap swaps the elements at the indexes i and j of the array at the path.
+// The other bytes of the json are kept as they are.
+//
+//	jj.Swap(`{"friends":["Andy","Carol"]}`, "friends", 0, 1)
+//	// {"friends":["Carol","Andy"]}
+func Swap(json, path string, i, j int, options ...SetOptions) (string, error) {
+	elems, err := arrayElements(json, path, options)
+	if err != nil {
+		return json, err
+	}
+	if i < 0 || i >= len(elems) || j < 0 || j >= len(elems) {
+		return json, &SetError{Err: ErrInvalidPath, Path: path, Reason: "array index out of range"}
+	}
+	if i == j {
+		return json, nil
+	}
+	a, b := elems[min(i, j)], elems[max(i, j)]
+	return json[:a.Index] + b.Raw + json[a.Index+len(a.Raw):b.Index] + a.Raw + json[b.Index+len(b.Raw):], nil
+}
+
+// RemoveValue removes the elements of the array at the path that are equal
+// to the value, and returns how many were removed. The order of the keys
+// and the formats of the numbers do not matter for equality.
+//
+//	jj.RemoveValue(`{"tags":["a","b","a"]}`, "tags", "a")
+//	// {"tags":["b"]}, 2
+func RemoveValue(json, path string, value interface{}, options ...SetOptions) (string, int, error) {
+	elems, err := arrayElements(json, path, options)
+	if err != nil {
+		return json, 0, err
+	}
+	var opts SetOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	sc := opts.config()
+	raw, err := valueRaw(value, &sc)
+	if err != nil {
+		return json, 0, err
+	}
+	if sc.stringify {
+		raw = string(appendStringify(nil, raw))
+	}
+	want := Parse(raw)
+	d := differ{opts: DiffOptions{IgnoreKeyOrder: true, NumericNumbers: true}}
+	var n int
+	for i := len(elems) - 1; i >= 0; i-- {
+		if d.equal(elems[i], want) {
+			json = string(appendDeleted(nil, json, elems[i]))
+			n++
+		}
+	}
+	return json, n, nil
+}
+
+// arrayElements returns the elements of the array at the path, with their
+// indexes into the json.
+func arrayElements(json, path string, options []SetOptions) ([]Result, error) {
+	var opts SetOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	arr := Get(json, path, ApplyGetOption(opts.PathOption))
+	if !arr.Exists() {
+		return nil, &SetError{Err: ErrPathNotFound, Path: path}
+	}
+	if !arr.IsArray() || arr.Index+len(arr.Raw) > len(json) || json[arr.Index:arr.Index+len(arr.Raw)] != arr.Raw {
+		return nil, &SetError{Err: ErrInvalidPath, Path: path, Reason: "path is not an array of the json"}
+	}
+	var elems []Result
+	arr.ForEach(func(_, value Result) bool {
+		elems = append(elems, value)
+		return true
+	})
+	return elems, nil
+}
@@ -0,0 +1,105 @@
+package jj
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetInsert(t *testing.T) {
+	tests := []struct {
+		json, path string
+		value      any
+		opts       SetOptions
+		expect     string
+	}{
+		{`{"a":[1, 2, 3]}`, "a.[+1]", 9, SetOptions{}, `{"a":[1, 9,2, 3]}`},
+		{`{"a":[1, 2, 3]}`, "a.[+0]", "x", SetOptions{}, `{"a":["x",1, 2, 3]}`},
+		{`{"a":[1, 2, 3]}`, "a.[+3]", 9, SetOptions{}, `{"a":[1,2,3,9]}`},
+		{`{"a":[1, 2, 3]}`, "a.1", 9, SetOptions{Insert: true}, `{"a":[1, 9,2, 3]}`},
+		{`{"a":[1, 2, 3]}`, "a.1", 9, SetOptions{Insert: true, Optimistic: true}, `{"a":[1, 9,2, 3]}`},
+		{`{"a":[1, 2, 3]}`, "a.1", 9, SetOptions{}, `{"a":[1, 9, 3]}`},
+		{`{"a":[]}`, "a.[+0]", 1, SetOptions{}, `{"a":[1]}`},
+		{`{}`, "a.[+0]", 1, SetOptions{}, `{"a":[1]}`},
+		{`{"a":[[1],[2]]}`, "a.1.[+0]", 0, SetOptions{}, `{"a":[[1],[0,2]]}`},
+		{`{"a":[{"t":[1]},{"t":[2]}]}`, "a.#.t.[+0]", 0, SetOptions{}, `{"a":[{"t":[0,1]},{"t":[0,2]}]}`},
+		{`[1,2]`, "[+1]", `{"b":1}`, SetOptions{}, `[1,"{\"b\":1}",2]`},
+		// a new element is inserted for the rest of the path
+		{`{"a":[{"x":1}]}`, "a.[+0].x", 9, SetOptions{}, `{"a":[{"x":9},{"x":1}]}`},
+		{`{"a":[{"x":1}]}`, "a.[+1].x", 9, SetOptions{}, `{"a":[{"x":1},{"x":9}]}`},
+		{`{"a":[1]}`, "a.[+0].0", "s", SetOptions{}, `{"a":[["s"],1]}`},
+	}
+	for _, tt := range tests {
+		got, err := Set(tt.json, tt.path, tt.value, tt.opts)
+		if err != nil || got != tt.expect {
+			t.Fatalf("path '%v': expected '%v', got '%v' %v", tt.path, tt.expect, got, err)
+		}
+	}
+	got, err := SetRaw(`[1,2]`, "[+1]", `{"b":1}`)
+	assert(t, err == nil && got == `[1,{"b":1},2]`)
+
+	// only arrays, up to right after their last element
+	for _, tt := range []struct{ json, path string }{
+		{`{"a":[1, 2, 3]}`, "a.[+4]"},
+		{`{"a":{"x":1}}`, "a.[+0]"},
+		{`{"a":1}`, "a.[+0]"},
+		{`{}`, "a.[+1]"},
+		{`{"a":[[1],{"x":1}]}`, "a.#.[+0]"},
+	} {
+		got, err := Set(tt.json, tt.path, 9)
+		var se *SetError
+		if !errors.As(err, &se) || se.Err != ErrInvalidPath || se.Path != tt.path || got != tt.json {
+			t.Fatalf("path '%v': expected an invalid path error, got '%v' %v", tt.path, got, err)
+		}
+	}
+}
+
+func TestPrepend(t *testing.T) {
+	got, err := Prepend(`{"friends":["Andy","Carol"]}`, "friends", "Sara")
+	assert(t, err == nil && got == `{"friends":["Sara","Andy","Carol"]}`)
+	got, err = Prepend(`{}`, "friends", "Sara")
+	assert(t, err == nil && got == `{"friends":["Sara"]}`)
+
+	raw := SetOptions{PathOption: PathOption{RawPath: true}}
+	got, err = Prepend(`{"a.b":[2]}`, "a.b", 1, raw)
+	assert(t, err == nil && got == `{"a.b":[1,2]}`)
+	got, err = Prepend(`{":a|#":[2],"a|#":[3]}`, ":a|#", 1, raw)
+	assert(t, err == nil && got == `{":a|#":[1,2],"a|#":[3]}`)
+}
+
+func TestSwap(t *testing.T) {
+	json := `{"a":[1, {"b":2},  "c"]}`
+	got, err := Swap(json, "a", 0, 2)
+	assert(t, err == nil && got == `{"a":["c", {"b":2},  1]}`)
+	got, err = Swap(json, "a", 2, 1)
+	assert(t, err == nil && got == `{"a":[1, "c",  {"b":2}]}`)
+	got, err = Swap(json, "a", 1, 1)
+	assert(t, err == nil && got == json)
+	_, err = Swap(json, "a", 0, 3)
+	assert(t, errors.Is(err, ErrInvalidPath))
+	_, err = Swap(json, "a.1", 0, 1)
+	assert(t, errors.Is(err, ErrInvalidPath))
+	_, err = Swap(json, "b", 0, 1)
+	assert(t, errors.Is(err, ErrPathNotFound))
+}
+
+func TestRemoveValue(t *testing.T) {
+	json := `{"tags":["a", "b", "a", {"x":1,"y":[2.0]}, 3]}`
+	tests := []struct {
+		value  any
+		n      int
+		expect string
+	}{
+		{"a", 2, `{"tags":[ "b", {"x":1,"y":[2.0]}, 3]}`},
+		{map[string]any{"y": []int{2}, "x": 1}, 1, `{"tags":["a", "b", "a", 3]}`},
+		{3.0, 1, `{"tags":["a", "b", "a", {"x":1,"y":[2.0]}]}`},
+		{"z", 0, json},
+	}
+	for _, tt := range tests {
+		got, n, err := RemoveValue(json, "tags", tt.value)
+		if err != nil || n != tt.n || got != tt.expect {
+			t.Fatalf("value '%v': expected '%v' %v, got '%v' %v %v", tt.value, tt.expect, tt.n, got, n, err)
+		}
+	}
+	_, _, err := RemoveValue(json, "missing", "a")
+	assert(t, errors.Is(err, ErrPathNotFound))
+}
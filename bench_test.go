@@ -58,6 +58,45 @@ func BenchmarkGJSONGet(t *testing.B) {
 	}
 }
 
+func BenchmarkGJSONGetCompiled(t *testing.B) {
+	paths := make([]*Path, len(benchPaths))
+	for j := 0; j < len(benchPaths); j++ {
+		paths[j] = CompilePath(benchPaths[j])
+	}
+	t.ReportAllocs()
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		for j := 0; j < len(paths); j++ {
+			if paths[j].Get(exampleJSON).Type == Null {
+				t.Fatal("did not find the value")
+			}
+		}
+	}
+}
+
+var benchQueryPath = `friends.#(nets.#(=="fb"))#.first|@reverse|0`
+
+func BenchmarkGJSONGetQuery(t *testing.B) {
+	t.ReportAllocs()
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		if Get(readmeJSON, benchQueryPath).Type == Null {
+			t.Fatal("did not find the value")
+		}
+	}
+}
+
+func BenchmarkGJSONGetQueryCompiled(t *testing.B) {
+	path := CompilePath(benchQueryPath)
+	t.ReportAllocs()
+	t.ResetTimer()
+	for i := 0; i < t.N; i++ {
+		if path.Get(readmeJSON).Type == Null {
+			t.Fatal("did not find the value")
+		}
+	}
+}
+
 func BenchmarkGJSONGetMany4Paths(t *testing.B) {
 	benchmarkGJSONGetManyN(t, 4)
 }
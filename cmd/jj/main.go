@@ -4,6 +4,7 @@ import (
 	"bytes"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -54,13 +55,16 @@ options:
      -JJ        Pure javascript object which has all string quoting leniently
      -k keypath JSON key path (like "name.last")
      -K keypath JSON key path as raw whole key
+     -diff a b  Print the differences of two JSON files, ignoring the key order and the number formats
+     -mv a b    Move the value at the key path a to the key path b
       keypath   Last argument for JSON key path`
 )
 
 type args struct {
 	infile, outfile, value *string
 
-	keypath, findRegex string
+	keypath, findRegex  string
+	diffFiles, moveArgs []string
 
 	raw, del, opt, keypathok, random      bool
 	ugly, notty, lines, rawKey, gen, expr bool
@@ -168,6 +172,18 @@ func parseArgs() args {
 			case "-f":
 				a.findRegex = os.Args[i]
 			}
+		case "-diff":
+			if i+2 >= len(os.Args) {
+				fail("two arguments are needed after: \"-diff\"")
+			}
+			a.diffFiles = os.Args[i+1 : i+3]
+			i += 2
+		case "-mv":
+			if i+2 >= len(os.Args) {
+				fail("two arguments are needed after: \"-mv\"")
+			}
+			a.moveArgs = os.Args[i+1 : i+3]
+			i += 2
 		case "--force-notty":
 			a.notty = true
 		case "--version":
@@ -224,6 +240,11 @@ func main() {
 	a := parseArgs()
 	f := a.createOutFile()
 
+	if a.diffFiles != nil {
+		a.printDiff(f)
+		return
+	}
+
 	outChan := make(chan Out)
 	go a.createOut(outChan)
 
@@ -246,6 +267,11 @@ func (a args) createOut(outChan chan Out) {
 		return
 	}
 
+	if a.streamable() {
+		a.streamGet(outChan)
+		return
+	}
+
 	var input []byte
 	var err error
 	if len(a.jsonMap) > 0 {
@@ -280,6 +306,28 @@ func (a args) createOut(outChan chan Out) {
 
 	opts := jj.SetOptions{PathOption: jj.PathOption{RawPath: a.rawKey}}
 
+	if a.moveArgs != nil {
+		var out Out
+		from, to := a.moveArgs[0], a.moveArgs[1]
+		var moved string
+		key, rename := renameKey(from, to, a.rawKey)
+		if rename {
+			moved, err = jj.Rename(string(input), from, key, opts)
+			// an array element has no key to rename, but can be moved
+			rename = !errors.Is(err, jj.ErrInvalidPath)
+		}
+		if !rename {
+			moved, err = jj.Move(string(input), from, to, opts)
+		}
+		if err != nil {
+			fail(err)
+		}
+		out.Data = []byte(moved)
+		outChan <- out
+		close(outChan)
+		return
+	}
+
 	if a.del {
 		var out Out
 		if out.Data, err = jj.DeleteBytes(input, a.keypath, opts); err != nil {
@@ -380,6 +428,41 @@ func (a args) createOut(outChan chan Out) {
 	return
 }
 
+// streamable tells if the value of the keypath is read from stdin, which is
+// streamed instead of being read into memory.
+func (a args) streamable() bool {
+	return a.infile == nil && len(a.jsonMap) == 0 && a.keypathok && a.value == nil &&
+		!a.del && a.moveArgs == nil && !a.gen && !a.expr && !a.iterateArray && !a.parseInnerJSONString && a.findRegex == ""
+}
+
+func (a args) streamGet(outChan chan Out) {
+	defer close(outChan)
+
+	var out Out
+	if !a.rawKey && strings.HasPrefix(a.keypath, "..") {
+		res, err := jj.GetReader(os.Stdin, a.keypath)
+		if err != nil {
+			fail(err)
+		}
+		a.assignOut(&out, res)
+		outChan <- out
+		return
+	}
+
+	rd := jj.NewReader(os.Stdin)
+	for {
+		res, err := rd.Get(a.keypath, jj.WithRawPath(a.rawKey))
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			fail(err)
+		}
+		a.assignOut(&out, res)
+		outChan <- out
+	}
+}
+
 func (a args) randomJSON(outChan chan Out) {
 	rand.Seed(time.Now().UnixNano())
 	randOptions := jj.DefaultRandOptions
@@ -504,6 +587,48 @@ func (a args) findKeyValues(input []byte) {
 	})
 }
 
+// renameKey returns the last key of the to path when the from path has the
+// same parent, so that -mv renames the key and keeps it at its position.
+func renameKey(from, to string, rawKey bool) (string, bool) {
+	if rawKey {
+		return to, true
+	}
+	fromParent, _, ok := splitLastKey(from)
+	if !ok {
+		return "", false
+	}
+	toParent, key, ok := splitLastKey(to)
+	if !ok || fromParent != toParent {
+		return "", false
+	}
+	return key, true
+}
+
+// splitLastKey splits a key path into its parent path and its unescaped last
+// key, or returns false when the path is not a plain path of keys.
+func splitLastKey(path string) (parent, key string, ok bool) {
+	var last []byte
+	start := 0
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '\\':
+			if i++; i < len(path) {
+				last = append(last, path[i])
+			}
+		case '.':
+			start, last = i+1, last[:0]
+		case '|', '#', '@', '*', '?', '[':
+			return "", "", false
+		default:
+			last = append(last, c)
+		}
+	}
+	if start == 0 {
+		return "", string(last), true
+	}
+	return path[:start-1], string(last), true
+}
+
 func (a args) assignOut(out *Out, res jj.Result) {
 	if a.raw {
 		out.Data = []byte(res.Raw)
@@ -554,15 +679,76 @@ func createInput(a args) ([]byte, error) {
 		return io.ReadAll(os.Stdin)
 	}
 
-	if stat, err := os.Stat(*a.infile); err == nil && !stat.IsDir() {
-		return os.ReadFile(*a.infile)
+	return readInput(*a.infile)
+}
+
+// readInput reads a file, or a @file, or returns the argument as the JSON.
+func readInput(name string) ([]byte, error) {
+	if stat, err := os.Stat(name); err == nil && !stat.IsDir() {
+		return os.ReadFile(name)
+	}
+
+	if strings.HasPrefix(name, "@") {
+		return os.ReadFile(name[1:])
+	}
+
+	return []byte(name), nil
+}
+
+// printDiff prints the differences of the two JSON files, and exits with 1
+// when there are some, like diff does.
+func (a args) printDiff(f *os.File) {
+	var docs [2][]byte
+	for i, name := range a.diffFiles {
+		data, err := readInput(name)
+		if err != nil {
+			fail(err)
+		}
+		if !jj.ValidBytes(data) {
+			fail(fmt.Errorf("invalid JSON: %s", name))
+		}
+		docs[i] = data
+	}
+
+	style := &jj.Style{}
+	if !a.notty && isatty.IsTerminal(f.Fd()) {
+		style = jj.TerminalStyle
 	}
 
-	if strings.HasPrefix(*a.infile, "@") {
-		return os.ReadFile((*a.infile)[1:])
+	changes := jj.Diff(docs[0], docs[1], jj.DiffOptions{IgnoreKeyOrder: true, NumericNumbers: true})
+	var b []byte
+	for _, c := range changes {
+		path := c.Path
+		if path == "" {
+			path = "@this"
+		}
+		switch c.Type {
+		case jj.Added:
+			b = appendColored(b, style.String, "+ ")
+			b = appendColored(b, style.Key, path)
+			b = appendColored(b, style.String, ": "+string(jj.Ugly([]byte(c.New))))
+		case jj.Removed:
+			b = appendColored(b, style.Null, "- ")
+			b = appendColored(b, style.Key, path)
+			b = appendColored(b, style.Null, ": "+string(jj.Ugly([]byte(c.Old))))
+		case jj.Changed:
+			b = appendColored(b, style.Number, "~ ")
+			b = appendColored(b, style.Key, path)
+			b = appendColored(b, style.Number, ": "+string(jj.Ugly([]byte(c.Old)))+" => "+string(jj.Ugly([]byte(c.New))))
+		}
+		b = append(b, '\n')
 	}
+	_, _ = f.Write(b)
+	_ = f.Close()
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+}
 
-	return []byte(*a.infile), nil
+func appendColored(b []byte, color [2]string, s string) []byte {
+	b = append(b, color[0]...)
+	b = append(b, s...)
+	return append(b, color[1]...)
 }
 
 func (a args) createOutFile() *os.File {
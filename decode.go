@@ -0,0 +1,442 @@
+package jj
+
+import (
+	"encoding"
+	"encoding/base64"
+	jsongo "encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DecodeError describes a value that could not be decoded into a Go value.
+type DecodeError struct {
+	// Path is the GJSON path of the value, relative to the decoded Result
+	// or, for DecodePath, to the json document.
+	Path string
+	// Value describes the json value, like "string" or "number 1.5".
+	Value string
+	// Type is the Go type that could not hold the value.
+	Type reflect.Type
+	// Err is the error of a json.Unmarshaler, a encoding.TextUnmarshaler, or
+	// a number conversion, if any.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	msg := "jj: cannot decode " + e.Value
+	if e.Type != nil {
+		msg += " into Go value of type " + e.Type.String()
+	}
+	if e.Path != "" {
+		msg += " at path " + strconv.Quote(e.Path)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Decode stores the value of the Result into the value pointed to by v, the
+// same way as json.Unmarshal does with the Raw json, but without parsing the
+// json again. Structs, maps, slices, arrays, interfaces and scalars are
+// supported, along with the `json` struct tags, including the "string"
+// option, and the json.Unmarshaler and encoding.TextUnmarshaler interfaces.
+//
+// A *DecodeError naming the path of the failing value is returned when a
+// value does not fit into its Go type.
+//
+//	var friend struct {
+//		First string   `json:"first"`
+//		Age   int      `json:"age"`
+//		Nets  []string `json:"nets"`
+//	}
+//	err := jj.Get(json, "friends.1").Decode(&friend)
+func (t Result) Decode(v interface{}) error {
+	return t.decode("", v)
+}
+
+// DecodePath gets the value of the path from the json and stores it into
+// the value pointed to by v. The empty path decodes the whole json. See
+// Result.Decode.
+func DecodePath(json, path string, v interface{}) error {
+	if path == "" {
+		return Parse(json).decode(path, v)
+	}
+	return Get(json, path).decode(path, v)
+}
+
+func (t Result) decode(path string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &DecodeError{Path: path, Value: "json", Type: reflect.TypeOf(v),
+			Err: &errorType{"a non-nil pointer is needed"}}
+	}
+	if !t.Exists() {
+		return &DecodeError{Path: path, Value: "json", Type: rv.Type(),
+			Err: &errorType{"value does not exist"}}
+	}
+	return decodeValue(path, t, rv.Elem())
+}
+
+var (
+	unmarshalerType     = reflect.TypeOf((*jsongo.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func decodeValue(path string, r Result, v reflect.Value) error {
+	if r.Type == Null {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.CanAddr() {
+		pv := v.Addr()
+		if pv.Type().Implements(unmarshalerType) {
+			if err := pv.Interface().(jsongo.Unmarshaler).UnmarshalJSON([]byte(r.Raw)); err != nil {
+				return decodeError(path, r, v, err)
+			}
+			return nil
+		}
+		if r.Type == String && pv.Type().Implements(textUnmarshalerType) {
+			if err := pv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(r.Str)); err != nil {
+				return decodeError(path, r, v, err)
+			}
+			return nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return decodeError(path, r, v, nil)
+		}
+		v.Set(reflect.ValueOf(r.Value()))
+	case reflect.Bool:
+		if r.Type != True && r.Type != False {
+			return decodeError(path, r, v, nil)
+		}
+		v.SetBool(r.Type == True)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if r.Type != Number {
+			return decodeError(path, r, v, nil)
+		}
+		n, err := strconv.ParseInt(r.Raw, 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			return decodeError(path, r, v, err)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if r.Type != Number {
+			return decodeError(path, r, v, nil)
+		}
+		n, err := strconv.ParseUint(r.Raw, 10, 64)
+		if err != nil || v.OverflowUint(n) {
+			return decodeError(path, r, v, err)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if r.Type != Number {
+			return decodeError(path, r, v, nil)
+		}
+		if v.OverflowFloat(r.Num) {
+			return decodeError(path, r, v, nil)
+		}
+		v.SetFloat(r.Num)
+	case reflect.String:
+		if r.Type != String {
+			return decodeError(path, r, v, nil)
+		}
+		v.SetString(r.Str)
+	case reflect.Struct:
+		if !r.IsObject() {
+			return decodeError(path, r, v, nil)
+		}
+		return decodeStruct(path, r, v)
+	case reflect.Map:
+		if !r.IsObject() {
+			return decodeError(path, r, v, nil)
+		}
+		return decodeMap(path, r, v)
+	case reflect.Slice:
+		if r.Type == String && v.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := base64.StdEncoding.DecodeString(r.Str)
+			if err != nil {
+				return decodeError(path, r, v, err)
+			}
+			v.SetBytes(b)
+			return nil
+		}
+		if !r.IsArray() {
+			return decodeError(path, r, v, nil)
+		}
+		return decodeSlice(path, r, v)
+	case reflect.Array:
+		if !r.IsArray() {
+			return decodeError(path, r, v, nil)
+		}
+		return decodeSlice(path, r, v)
+	default:
+		return decodeError(path, r, v, nil)
+	}
+	return nil
+}
+
+func decodeError(path string, r Result, v reflect.Value, err error) error {
+	var value string
+	switch r.Type {
+	case String:
+		value = "string"
+	case Number:
+		value = "number " + r.Raw
+	case True, False:
+		value = "bool"
+	default:
+		if r.IsArray() {
+			value = "array"
+		} else {
+			value = "object"
+		}
+	}
+	return &DecodeError{Path: path, Value: value, Type: v.Type(), Err: err}
+}
+
+// joinPath appends a key or an index to a GJSON path.
+func joinPath(path, comp string) string {
+	if path == "" {
+		return escapeComp(comp)
+	}
+	return path + "." + escapeComp(comp)
+}
+
+func decodeStruct(path string, r Result, v reflect.Value) error {
+	fields := cachedFields(v.Type())
+	var err error
+	r.ForEach(func(key, value Result) bool {
+		f := fields.lookup(key.Str)
+		if f == nil {
+			return true
+		}
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok {
+			return true
+		}
+		if f.str && value.Type == String {
+			// the ",string" option holds a scalar inside of a string
+			value = Parse(value.Str)
+		}
+		err = decodeValue(joinPath(path, key.Str), value, fv)
+		return err == nil
+	})
+	return err
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex allocating the nil pointers
+// to embedded structs. It fails for the pointers to unexported structs.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return v, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func decodeMap(path string, r Result, v reflect.Value) error {
+	t := v.Type()
+	kt := t.Key()
+	switch {
+	case kt.Kind() == reflect.String, reflect.PtrTo(kt).Implements(textUnmarshalerType):
+	case kt.Kind() >= reflect.Int && kt.Kind() <= reflect.Uintptr:
+	default:
+		return decodeError(path, r, v, nil)
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	var err error
+	r.ForEach(func(key, value Result) bool {
+		kpath := joinPath(path, key.Str)
+		elem := reflect.New(t.Elem()).Elem()
+		if err = decodeValue(kpath, value, elem); err != nil {
+			return false
+		}
+		kv := reflect.New(kt).Elem()
+		switch {
+		case reflect.PtrTo(kt).Implements(textUnmarshalerType):
+			err = kv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key.Str))
+		case kt.Kind() == reflect.String:
+			kv.SetString(key.Str)
+		default:
+			err = decodeValue(kpath, Parse(key.Str), kv)
+		}
+		if err != nil {
+			if _, ok := err.(*DecodeError); !ok {
+				err = decodeError(kpath, key, kv, err)
+			}
+			return false
+		}
+		v.SetMapIndex(kv, elem)
+		return true
+	})
+	return err
+}
+
+func decodeSlice(path string, r Result, v reflect.Value) error {
+	var i int
+	var err error
+	r.ForEach(func(_, value Result) bool {
+		if v.Kind() == reflect.Slice {
+			if i >= v.Cap() {
+				nv := reflect.MakeSlice(v.Type(), v.Len(), 2*v.Cap()+4)
+				reflect.Copy(nv, v)
+				v.Set(nv)
+			}
+			v.SetLen(i + 1)
+		} else if i >= v.Len() {
+			// the rest of the values do not fit into the array
+			return false
+		}
+		elem := v.Index(i)
+		elem.Set(reflect.Zero(elem.Type()))
+		err = decodeValue(joinPath(path, strconv.Itoa(i)), value, elem)
+		i++
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	if v.Kind() == reflect.Slice {
+		if i == 0 {
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+		} else {
+			v.SetLen(i)
+		}
+	} else {
+		for ; i < v.Len(); i++ {
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		}
+	}
+	return nil
+}
+
+// decodeField is a struct field, or a field of an embedded struct, that
+// can be decoded.
+type decodeField struct {
+	name  string
+	index []int
+	str   bool // the ",string" option
+}
+
+type decodeFields struct {
+	list   []decodeField
+	byName map[string]*decodeField
+}
+
+// lookup finds a field by its exact name, and otherwise by a case
+// insensitive match, like json.Unmarshal does.
+func (fs *decodeFields) lookup(key string) *decodeField {
+	if f, ok := fs.byName[key]; ok {
+		return f
+	}
+	for i := range fs.list {
+		if strings.EqualFold(fs.list[i].name, key) {
+			return &fs.list[i]
+		}
+	}
+	return nil
+}
+
+var decodeFieldCache sync.Map // map[reflect.Type]*decodeFields
+
+func cachedFields(t reflect.Type) *decodeFields {
+	if fs, ok := decodeFieldCache.Load(t); ok {
+		return fs.(*decodeFields)
+	}
+	fs := &decodeFields{byName: make(map[string]*decodeField)}
+	depth := make(map[string]int)
+	collectFields(t, nil, fs, depth)
+	for i := range fs.list {
+		fs.byName[fs.list[i].name] = &fs.list[i]
+	}
+	v, _ := decodeFieldCache.LoadOrStore(t, fs)
+	return v.(*decodeFields)
+}
+
+// collectFields gathers the fields of a struct, promoting the fields of the
+// untagged embedded structs. A field at a shallower depth hides the fields
+// of the same name that are deeper.
+func collectFields(t reflect.Type, index []int, fs *decodeFields, depth map[string]int) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if sf.Anonymous && name == "" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, sf)
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if d, ok := depth[name]; ok && d <= len(index) {
+			continue
+		}
+		depth[name] = len(index)
+		f := decodeField{name: name, index: append(append([]int{}, index...), i)}
+		for _, opt := range strings.Split(opts, ",") {
+			f.str = f.str || opt == "string"
+		}
+		fs.replace(f)
+	}
+	for _, sf := range embedded {
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		collectFields(ft, append(append([]int{}, index...), sf.Index...), fs, depth)
+	}
+}
+
+func (fs *decodeFields) replace(f decodeField) {
+	for i := range fs.list {
+		if fs.list[i].name == f.name {
+			fs.list[i] = f
+			return
+		}
+	}
+	fs.list = append(fs.list, f)
+}
@@ -0,0 +1,121 @@
+package jj
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type decodeName struct {
+	First string `json:"first"`
+	Last  string `json:"last"`
+}
+
+type decodePerson struct {
+	Name     decodeName        `json:"name"`
+	Age      int               `json:"age"`
+	Children []string          `json:"children"`
+	Movie    *string           `json:"fav.movie"`
+	Friends  []decodeFriend    `json:"friends"`
+	Extra    map[string]string `json:"extra"`
+	Skip     string            `json:"-"`
+}
+
+type decodeFriend struct {
+	First string
+	Age   uint8 `json:"age"`
+	Nets  [2]string
+}
+
+func TestDecode(t *testing.T) {
+	var p decodePerson
+	p.Skip = "keep"
+	if err := Parse(readmeJSON).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	var expect decodePerson
+	if err := json.Unmarshal([]byte(readmeJSON), &expect); err != nil {
+		t.Fatal(err)
+	}
+	expect.Skip = "keep"
+	assert(t, reflect.DeepEqual(p, expect))
+	assert(t, p.Friends[2].Nets == [2]string{"ig", "tw"} && *p.Movie == "Deer Hunter")
+
+	var nets []string
+	assert(t, DecodePath(readmeJSON, "friends.0.nets", &nets) == nil)
+	assert(t, reflect.DeepEqual(nets, []string{"ig", "fb", "tw"}))
+
+	var any interface{}
+	assert(t, DecodePath(readmeJSON, "name", &any) == nil)
+	assert(t, reflect.DeepEqual(any, map[string]interface{}{"first": "Tom", "last": "Anderson"}))
+
+	var ages map[string]int
+	assert(t, Get(readmeJSON, `{Dale:friends.0.age,Roger:friends.1.age}`).Decode(&ages) == nil)
+	assert(t, reflect.DeepEqual(ages, map[string]int{"Dale": 44, "Roger": 68}))
+
+	var ids map[int]bool
+	assert(t, Parse(`{"1":true,"-2":false}`).Decode(&ids) == nil)
+	assert(t, reflect.DeepEqual(ids, map[int]bool{1: true, -2: false}))
+}
+
+func TestDecodeOptions(t *testing.T) {
+	type embedded struct {
+		ID   int `json:"id"`
+		Name string
+	}
+	var v struct {
+		embedded
+		Name  string          `json:"name"`
+		Count int64           `json:"count,string"`
+		When  time.Time       `json:"when"`
+		Raw   json.RawMessage `json:"raw"`
+		Data  []byte          `json:"data"`
+		Ptr   *int            `json:"ptr"`
+	}
+	one := 1
+	v.Ptr = &one
+	err := Parse(`{"id":7,"NAME":"x","count":"12","when":"2024-01-02T03:04:05Z",
+		"raw":{"a": [1]},"data":"aGk=","ptr":null}`).Decode(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, v.ID == 7 && v.embedded.Name == "")
+	assert(t, v.Name == "x" && v.Count == 12 && v.When.Year() == 2024)
+	assert(t, string(v.Raw) == `{"a": [1]}` && string(v.Data) == "hi" && v.Ptr == nil)
+}
+
+func TestDecodeErrors(t *testing.T) {
+	var p decodePerson
+	tests := []struct {
+		json string
+		path string
+		v    interface{}
+		msg  string
+	}{
+		{`{"friends":[{},{"age":300}]}`, "", &p, `friends.1.age`},
+		{`{"friends":[{"age":-1}]}`, "", &p, `friends.0.age`},
+		{`{"name":{"first":1}}`, "", &p, `name.first`},
+		{`{"a":{"b.c":[true]}}`, "a", &map[string][]int{}, `a.b\.c.0`},
+		{`{"a":"1"}`, "a", new(int), `a`},
+		{`{"a":1}`, "b", new(int), `b`},
+		{`{"a":1.5}`, "a", new(int), `a`},
+		{`{"a":1}`, "a", 0, `a`},
+		{`{"x":1}`, "", &map[bool]int{}, ``},
+	}
+	for _, tt := range tests {
+		err := DecodePath(tt.json, tt.path, tt.v)
+		var de *DecodeError
+		if !errors.As(err, &de) {
+			t.Fatalf("json '%v': expected a DecodeError, got '%v'", tt.json, err)
+		}
+		if de.Path != tt.msg {
+			t.Fatalf("json '%v': expected '%v', got '%v'", tt.json, tt.msg, de.Path)
+		}
+	}
+	assert(t, DecodePath(readmeJSON, "", &p) == nil && p.Age == 37)
+	err := DecodePath(`{"friends":[{"age":"x"}]}`, "friends", &p.Friends)
+	assert(t, err != nil && strings.Contains(err.Error(), `"friends.0.age"`))
+}
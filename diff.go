@@ -0,0 +1,313 @@
+package jj
+
+import "strconv"
+
+// DiffType is the kind of a Change between two json documents.
+type DiffType int
+
+const (
+	// Added is a value that is in the new document only.
+	Added DiffType = iota + 1
+	// Removed is a value that is in the old document only.
+	Removed
+	// Changed is a value that is different in the new document.
+	Changed
+)
+
+func (t DiffType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return ""
+}
+
+// Change is a difference between two json documents.
+type Change struct {
+	Type DiffType
+	// Path is the GJSON path of the value, which is empty for the whole
+	// document. The indexes of the removed elements are the ones of the old
+	// document, and the others are the ones of the new document.
+	Path string
+	// Old and New are the raw values. Old is empty for an Added value, and
+	// New is empty for a Removed one.
+	Old, New string
+}
+
+// DiffOptions are the options of the Diff function.
+type DiffOptions struct {
+	// IgnoreKeyOrder ignores the order of the keys of the objects. Otherwise
+	// an object with its keys in another order is Changed, besides the
+	// changes of its values.
+	IgnoreKeyOrder bool
+	// IgnoreArrayOrder matches the elements of the arrays wherever they are,
+	// by their ArrayKey, or by their values when they have none.
+	IgnoreArrayOrder bool
+	// ArrayKey is the path of the key of the elements of the arrays, like
+	// "id", for IgnoreArrayOrder.
+	ArrayKey string
+	// NumericNumbers compares the numbers by their values, so that 1.0 and
+	// 1 are equal. Otherwise the numbers are compared by their raw text.
+	NumericNumbers bool
+}
+
+// Diff returns the differences between the json documents a and b, in the
+// order of the documents. The removed and changed values come in the order
+// of a, and the added values after the others of the same object or array.
+//
+//	jj.Diff([]byte(`{"a":1,"b":[1]}`), []byte(`{"a":2,"b":[1,2]}`))
+//	// [{changed a 1 2} {added b.1  2}]
+func Diff(a, b []byte, options ...DiffOptions) []Change {
+	var d differ
+	if len(options) > 0 {
+		d.opts = options[0]
+	}
+	d.diff("", ParseBytes(a), ParseBytes(b))
+	return d.changes
+}
+
+type differ struct {
+	opts    DiffOptions
+	pointer bool // JSON Pointer paths, in the order of a JSON Patch
+	changes []Change
+}
+
+// join appends a key or an index to a path.
+func (d *differ) join(path, comp string) string {
+	if d.pointer {
+		return string(appendPointerToken([]byte(path), comp))
+	}
+	return joinPath(path, comp)
+}
+
+func (d *differ) add(typ DiffType, path string, a, b Result) {
+	d.changes = append(d.changes, Change{Type: typ, Path: path, Old: a.Raw, New: b.Raw})
+}
+
+func (d *differ) diff(path string, a, b Result) {
+	switch {
+	case !a.Exists() && !b.Exists():
+	case !a.Exists():
+		d.add(Added, path, a, b)
+	case !b.Exists():
+		d.add(Removed, path, a, b)
+	case a.IsObject() && b.IsObject():
+		d.diffObjects(path, a, b)
+	case a.IsArray() && b.IsArray():
+		d.diffArrays(path, a, b)
+	case !d.equalValues(a, b):
+		d.add(Changed, path, a, b)
+	}
+}
+
+// equal returns true when a and b have no differences.
+func (d *differ) equal(a, b Result) bool {
+	e := differ{opts: d.opts, pointer: d.pointer}
+	e.diff("", a, b)
+	return len(e.changes) == 0
+}
+
+// equalValues compares the values that are not both objects or arrays.
+func (d *differ) equalValues(a, b Result) bool {
+	if a.Type != b.Type || a.IsJSON() || b.IsJSON() {
+		return false
+	}
+	switch a.Type {
+	case Number:
+		if d.opts.NumericNumbers {
+			return a.BigFloat().Cmp(b.BigFloat()) == 0
+		}
+		return a.Raw == b.Raw
+	case String:
+		return a.Str == b.Str
+	}
+	return true
+}
+
+func (d *differ) diffObjects(path string, a, b Result) {
+	akeys, avalues := objectMembers(a)
+	bkeys, bvalues := objectMembers(b)
+	if !d.opts.IgnoreKeyOrder && !sameOrder(akeys, bkeys, avalues, bvalues) {
+		d.add(Changed, path, a, b)
+	}
+	for _, key := range akeys {
+		if bv, ok := bvalues[key]; ok {
+			d.diff(d.join(path, key), avalues[key], bv)
+		} else {
+			d.add(Removed, d.join(path, key), avalues[key], Result{})
+		}
+	}
+	for _, key := range bkeys {
+		if _, ok := avalues[key]; !ok {
+			d.add(Added, d.join(path, key), Result{}, bvalues[key])
+		}
+	}
+}
+
+// objectMembers returns the keys of an object in their order, and their
+// values. A duplicated key has its first value, like Get does.
+func objectMembers(obj Result) (keys []string, values map[string]Result) {
+	values = make(map[string]Result)
+	obj.ForEach(func(key, value Result) bool {
+		if _, ok := values[key.Str]; !ok {
+			keys = append(keys, key.Str)
+			values[key.Str] = value
+		}
+		return true
+	})
+	return keys, values
+}
+
+// sameOrder returns true when the keys that are in both objects are in the
+// same order.
+func sameOrder(akeys, bkeys []string, avalues, bvalues map[string]Result) bool {
+	var i int
+	for _, key := range bkeys {
+		if _, ok := avalues[key]; !ok {
+			continue
+		}
+		for ; ; i++ {
+			if _, ok := bvalues[akeys[i]]; ok {
+				break
+			}
+		}
+		if akeys[i] != key {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+func (d *differ) diffArrays(path string, a, b Result) {
+	aa, ba := a.Array(), b.Array()
+	if d.pointer && !d.opts.IgnoreArrayOrder {
+		d.patchArrays(path, aa, ba)
+		return
+	}
+	if !d.opts.IgnoreArrayOrder {
+		for i := 0; i < len(aa) && i < len(ba); i++ {
+			d.diff(d.join(path, strconv.Itoa(i)), aa[i], ba[i])
+		}
+		for i := len(aa); i < len(ba); i++ {
+			d.add(Added, d.join(path, strconv.Itoa(i)), Result{}, ba[i])
+		}
+		for i := len(ba); i < len(aa); i++ {
+			d.add(Removed, d.join(path, strconv.Itoa(i)), aa[i], Result{})
+		}
+		return
+	}
+	matched := make([]bool, len(ba))
+	for i, av := range aa {
+		j := d.match(av, ba, matched)
+		if j < 0 {
+			d.add(Removed, d.join(path, strconv.Itoa(i)), av, Result{})
+			continue
+		}
+		matched[j] = true
+		d.diff(d.join(path, strconv.Itoa(j)), av, ba[j])
+	}
+	for j, bv := range ba {
+		if !matched[j] {
+			d.add(Added, d.join(path, strconv.Itoa(j)), Result{}, bv)
+		}
+	}
+}
+
+// maxPatchCells is the largest table of the longest common subsequence of
+// two arrays in a patch, beyond which the elements are compared by position.
+const maxPatchCells = 1 << 22
+
+// patchArrays adds the changes of a patch that turn the elements aa into ba,
+// keeping the longest common subsequence of the equal elements. The other
+// elements between two kept ones are diffed by position, and the extra ones
+// are removed or added. The indexes are the ones of the array as the patch
+// changes it, one operation after the other.
+func (d *differ) patchArrays(path string, aa, ba []Result) {
+	// the common prefix and suffix are kept without a table
+	pre := 0
+	for pre < len(aa) && pre < len(ba) && d.equal(aa[pre], ba[pre]) {
+		pre++
+	}
+	suf := 0
+	for suf < len(aa)-pre && suf < len(ba)-pre && d.equal(aa[len(aa)-1-suf], ba[len(ba)-1-suf]) {
+		suf++
+	}
+	am, bm := aa[pre:len(aa)-suf], ba[pre:len(ba)-suf]
+	var kept [][2]int // the indexes of the kept pairs in am and bm
+	if (len(am)+1)*(len(bm)+1) <= maxPatchCells {
+		// lcs[i][j] is the length of the subsequence of am[i:] and bm[j:]
+		lcs := make([][]int, len(am)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(bm)+1)
+		}
+		for i := len(am) - 1; i >= 0; i-- {
+			for j := len(bm) - 1; j >= 0; j-- {
+				switch {
+				case d.equal(am[i], bm[j]):
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				case lcs[i+1][j] >= lcs[i][j+1]:
+					lcs[i][j] = lcs[i+1][j]
+				default:
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+		for i, j := 0, 0; i < len(am) && j < len(bm); {
+			switch {
+			case lcs[i][j] == lcs[i+1][j+1]+1 && d.equal(am[i], bm[j]):
+				kept = append(kept, [2]int{i, j})
+				i, j = i+1, j+1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				i++
+			default:
+				j++
+			}
+		}
+	}
+	kept = append(kept, [2]int{len(am), len(bm)})
+	pos, i, j := pre, 0, 0
+	for _, k := range kept {
+		// the elements between the kept ones
+		for ; i < k[0] && j < k[1]; i, j = i+1, j+1 {
+			d.diff(d.join(path, strconv.Itoa(pos)), am[i], bm[j])
+			pos++
+		}
+		// the last first, so that the indexes of a patch hold
+		for n := pos + k[0] - i - 1; n >= pos; n-- {
+			d.add(Removed, d.join(path, strconv.Itoa(n)), am[n-pos+i], Result{})
+		}
+		for ; j < k[1]; j++ {
+			d.add(Added, d.join(path, strconv.Itoa(pos)), Result{}, bm[j])
+			pos++
+		}
+		i, j = k[0]+1, k[1]+1
+		pos++
+	}
+}
+
+// match returns the position of the first element of ba that is not matched
+// yet, and has the same key as av, or the same value when av has no key.
+func (d *differ) match(av Result, ba []Result, matched []bool) int {
+	var key Result
+	if d.opts.ArrayKey != "" {
+		key = av.Get(d.opts.ArrayKey)
+	}
+	for j, bv := range ba {
+		if matched[j] {
+			continue
+		}
+		if key.Exists() {
+			if d.equal(key, bv.Get(d.opts.ArrayKey)) {
+				return j
+			}
+		} else if d.equal(av, bv) {
+			return j
+		}
+	}
+	return -1
+}
@@ -0,0 +1,68 @@
+package jj
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		opts   DiffOptions
+		expect []Change
+	}{
+		{`{"a":1}`, `{"a":1}`, DiffOptions{}, nil},
+		{`{"a":1,"b":[1]}`, `{"a":2,"b":[1,2]}`, DiffOptions{}, []Change{
+			{Changed, "a", "1", "2"}, {Added, "b.1", "", "2"},
+		}},
+		{`{"a":{"x":true,"y":"s"},"c.d":null}`, `{"a":{"x":false},"e":[]}`, DiffOptions{}, []Change{
+			{Changed, "a.x", "true", "false"}, {Removed, "a.y", `"s"`, ""},
+			{Removed, `c\.d`, "null", ""}, {Added, "e", "", "[]"},
+		}},
+		{`{"a":1,"b":2,"c":3}`, `{"b":2,"a":1,"d":4}`, DiffOptions{}, []Change{
+			{Changed, "", `{"a":1,"b":2,"c":3}`, `{"b":2,"a":1,"d":4}`},
+			{Removed, "c", "3", ""}, {Added, "d", "", "4"},
+		}},
+		{`{"a":1,"b":2,"c":3}`, `{"b":2,"a":1,"d":4}`, DiffOptions{IgnoreKeyOrder: true}, []Change{
+			{Removed, "c", "3", ""}, {Added, "d", "", "4"},
+		}},
+		{`{"a":1,"c":3,"b":2}`, `{"a":1,"b":2}`, DiffOptions{}, []Change{
+			{Removed, "c", "3", ""},
+		}},
+		{`[1.0,"A",{"a":1}]`, `[1,"A",[1]]`, DiffOptions{}, []Change{
+			{Changed, "0", "1.0", "1"}, {Changed, "2", `{"a":1}`, "[1]"},
+		}},
+		{`[1.0,1e2,0.10]`, `[1,100,0.1]`, DiffOptions{NumericNumbers: true}, nil},
+		{`[1,2,3]`, `[3,1,4]`, DiffOptions{IgnoreArrayOrder: true}, []Change{
+			{Removed, "1", "2", ""}, {Added, "2", "", "4"},
+		}},
+		{`[1,1,2]`, `[2,1]`, DiffOptions{IgnoreArrayOrder: true}, []Change{
+			{Removed, "1", "1", ""},
+		}},
+		{
+			`{"u":[{"id":1,"n":"a"},{"id":2,"n":"b"},{"n":"c"}]}`,
+			`{"u":[{"id":2,"n":"B"},{"id":3,"n":"d"},{"id":1,"n":"a"},{"n":"c"}]}`,
+			DiffOptions{IgnoreArrayOrder: true, ArrayKey: "id"},
+			[]Change{{Changed, "u.0.n", `"b"`, `"B"`}, {Added, "u.1", "", `{"id":3,"n":"d"}`}},
+		},
+		{`[{"id":1.0}]`, `[{"id":1}]`, DiffOptions{IgnoreArrayOrder: true, ArrayKey: "id", NumericNumbers: true}, nil},
+		{``, `{}`, DiffOptions{}, []Change{{Added, "", "", "{}"}}},
+		{`1`, ``, DiffOptions{}, []Change{{Removed, "", "1", ""}}},
+		{`"x"`, `"y"`, DiffOptions{}, []Change{{Changed, "", `"x"`, `"y"`}}},
+	}
+	for _, tt := range tests {
+		got := Diff([]byte(tt.a), []byte(tt.b), tt.opts)
+		if !reflect.DeepEqual(got, tt.expect) {
+			t.Fatalf("diff '%v' '%v': expected '%v', got '%v'", tt.a, tt.b, tt.expect, got)
+		}
+		for _, c := range got {
+			if c.Type == Removed || (c.Type == Changed && !tt.opts.IgnoreArrayOrder) {
+				assert(t, Get(tt.a, c.Path).Raw == c.Old || c.Path == "")
+			}
+			if c.Type != Removed && !tt.opts.IgnoreArrayOrder {
+				assert(t, Get(tt.b, c.Path).Raw == c.New || c.Path == "")
+			}
+		}
+	}
+	assert(t, Added.String() == "added" && Changed.String() == "changed" && DiffType(0).String() == "")
+}
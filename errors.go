@@ -0,0 +1,212 @@
+package jj
+
+import (
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPath is the error of a path with an invalid syntax.
+	ErrInvalidPath = &errorType{"invalid path"}
+	// ErrUnknownModifier is the error of a path with a modifier that does
+	// not exist.
+	ErrUnknownModifier = &errorType{"unknown modifier"}
+	// ErrPathNotFound is the error of a path that does not exist in a json.
+	ErrPathNotFound = &errorType{"path not found"}
+	// ErrConflict is the error of a conditional set, when the current value
+	// is not the expected one.
+	ErrConflict = &errorType{"conflict"}
+)
+
+// GetError is the error of GetE. It wraps one of ErrInvalidJSON,
+// ErrInvalidPath, ErrUnknownModifier and ErrPathNotFound, which can be
+// checked with errors.Is.
+type GetError struct {
+	Err error
+	// Offset is the byte offset in the json where it is invalid.
+	Offset int
+	// Column is the column in the path, from 1, where it is invalid, or of
+	// the component that is unknown or not found.
+	Column int
+	// Reason tells why the path is invalid.
+	Reason string
+	// Component is the component of the path that is unknown or not found,
+	// like "@foo" or "last".
+	Component string
+	// Resolved is the deepest part of the path that resolved before the
+	// Component, which is empty when the first component is not found.
+	Resolved string
+}
+
+func (e *GetError) Error() string {
+	switch e.Err {
+	case ErrInvalidJSON:
+		return "invalid json at offset " + strconv.Itoa(e.Offset)
+	case ErrInvalidPath:
+		return "invalid path at column " + strconv.Itoa(e.Column) + ": " + e.Reason
+	}
+	msg := e.Err.Error() + " " + strconv.Quote(e.Component) + " at column " + strconv.Itoa(e.Column)
+	if e.Resolved != "" {
+		msg += " after " + strconv.Quote(e.Resolved)
+	}
+	return msg
+}
+
+func (e *GetError) Unwrap() error {
+	return e.Err
+}
+
+// GetE searches json for the specified path like Get, and tells why there
+// is no value with a *GetError, or with the *ModifierError of a failing
+// modifier.
+//
+//	_, err := jj.GetE(`{"name":{"first":"Tom"}}`, "name.last")
+//	// path not found "last" at column 6 after "name"
+//	errors.Is(err, jj.ErrPathNotFound) // true
+//
+// Unlike Get, it checks that the whole json is valid first.
+func GetE(json, path string, optionsFns ...PathOptionFn) (Result, error) {
+	var modErr error
+	option := GetOptionFns(optionsFns).Apply(&PathOption{})
+	option.modErr = &modErr
+	lines := !option.RawPath && strings.HasPrefix(path, "..")
+	if offset, ok := validOffset(json, lines); !ok {
+		return Result{}, &GetError{Err: ErrInvalidJSON, Offset: offset}
+	}
+	var cuts []int
+	if !option.RawPath {
+		var err error
+		if cuts, err = scanPath(path); err != nil {
+			return Result{}, err
+		}
+	}
+	res := getPath(json, path, option)
+	res.useNumber = option.UseNumber
+	if modErr != nil {
+		return Result{}, modErr
+	}
+	if res.Exists() {
+		return res, nil
+	}
+	// find the first component that does not resolve
+	var prefix int
+	if lines {
+		prefix = 2
+	}
+	start, end := prefix, len(path)
+	for _, cut := range cuts {
+		if !getPath(json, path[:cut], option).Exists() {
+			end = cut
+			break
+		}
+		start = cut + 1
+	}
+	e := &GetError{Err: ErrPathNotFound, Component: path[start:end], Column: start + 1}
+	if start > prefix {
+		e.Resolved = path[:start-1]
+	}
+	if comp := e.Component; !option.RawPath && !DisableModifiers && len(comp) > 1 && comp[0] == '@' {
+		name, _, _ := parseModifier(comp, option.modifiers())
+		if !option.modifiers().Exists(name) {
+			e.Err = ErrUnknownModifier
+		}
+	}
+	return Result{}, e
+}
+
+// validOffset checks that json is valid, or that all of its lines are, and
+// returns the offset where it is not.
+func validOffset(json string, lines bool) (int, bool) {
+	data := stringBytes(json)
+	if !lines {
+		_, i, ok := ValidPayload(data, 0)
+		return min(i, len(data)), ok
+	}
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case ' ', '\t', '\n', '\r':
+			continue
+		}
+		var ok bool
+		if _, i, ok = validAny(data, i); !ok {
+			return min(i, len(data)), false
+		}
+		i--
+	}
+	return len(data), true
+}
+
+// scanPath checks the syntax of a path, and returns the positions of the
+// dots and pipes that separate its components, outside of the queries, the
+// sub-selectors and the arguments of the modifiers.
+func scanPath(path string) (cuts []int, err error) {
+	invalid := func(i int, reason string) ([]int, error) {
+		return nil, &GetError{Err: ErrInvalidPath, Column: i + 1, Reason: reason}
+	}
+	if path == "" {
+		return invalid(0, "empty path")
+	}
+	var stack []int // the positions of the open brackets
+	compStart, i := 0, 0
+	if strings.HasPrefix(path, "..") {
+		compStart, i = 2, 2
+	}
+	for ; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '\\':
+			if i++; i == len(path) {
+				return invalid(i-1, "escape at the end")
+			}
+		case c == '"' && len(stack) > 0:
+			j := i + 1
+			for ; j < len(path) && path[j] != '"'; j++ {
+				if path[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(path) {
+				return invalid(i, "unterminated string")
+			}
+			i = j
+		case c == '(' && (len(stack) > 0 || (i > 0 && path[i-1] == '#')),
+			(c == '[' || c == '{') && (len(stack) > 0 || i == compStart):
+			stack = append(stack, i)
+		case c == ')' || c == ']' || c == '}':
+			if len(stack) == 0 {
+				continue
+			}
+			open := stack[len(stack)-1]
+			switch path[open] {
+			case '(':
+				if c != ')' {
+					return invalid(i, "unexpected "+string(c))
+				}
+				if q := path[open+1 : i]; len(stack) == 1 && isExtendedQuery(q) {
+					if _, ok := cachedQueryExpr(q).(queryInvalid); ok {
+						return invalid(open+1, "invalid query")
+					}
+				}
+			case '[':
+				if c != ']' {
+					return invalid(i, "unexpected "+string(c))
+				}
+			case '{':
+				if c != '}' {
+					return invalid(i, "unexpected "+string(c))
+				}
+			}
+			stack = stack[:len(stack)-1]
+		case len(stack) > 0:
+		case c == ':' && path[compStart] == '@':
+			compStart = i + 1
+		case c == '.' || c == '|':
+			cuts = append(cuts, i)
+			compStart = i + 1
+		}
+	}
+	if len(stack) > 0 {
+		return invalid(stack[len(stack)-1], "unclosed "+string(path[stack[len(stack)-1]]))
+	}
+	return cuts, nil
+}
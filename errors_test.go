@@ -0,0 +1,76 @@
+package jj
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetE(t *testing.T) {
+	json := `{"name":{"first":"Tom","last":"Anderson"},"friends":[{"first":"Dale","nets":["ig"]},{"first":"Jane"}],"@odd":1}`
+	tests := []struct {
+		json   string
+		path   string
+		expect string // the raw result, or the error
+	}{
+		{json, `name.last`, `"Anderson"`},
+		{json, `friends.#(first=="Jane").first`, `"Jane"`},
+		{json, `name.middle`, `path not found "middle" at column 6 after "name"`},
+		{json, `nick`, `path not found "nick" at column 1`},
+		{json, `friends.0.nets.1`, `path not found "1" at column 16 after "friends.0.nets"`},
+		{json, `friends.#(first=="Bob").first`, `path not found "#(first==\"Bob\")" at column 9 after "friends"`},
+		{json, `name|@nope`, `unknown modifier "@nope" at column 6 after "name"`},
+		{json, `@nope:{"a.b":1}.x`, `unknown modifier "@nope:{\"a.b\":1}" at column 1`},
+		{json, `@odd`, `1`},
+		{json, `name.first|@reverse.x`, `path not found "x" at column 21 after "name.first|@reverse"`},
+		{json, `friends.#(first=="Jane"`, `invalid path at column 10: unclosed (`},
+		{json, `friends.#(first=="Jane)`, `invalid path at column 18: unterminated string`},
+		{json, `[name.first,nick`, `invalid path at column 1: unclosed [`},
+		{json, `{a:name.first]`, `invalid path at column 14: unexpected ]`},
+		{json, `name\`, `invalid path at column 5: escape at the end`},
+		{json, ``, `invalid path at column 1: empty path`},
+		{`{"a":1,}`, `a`, `invalid json at offset 7`},
+		{`{"a":[1,2}`, `a`, `invalid json at offset 9`},
+		{``, `a`, `invalid json at offset 0`},
+		{"{\"a\":1}\n{\"a\":2}", `..1.a`, `2`},
+		{"{\"a\":1}\n{\"b\":2}", `..1.a`, `path not found "a" at column 5 after "..1"`},
+		{"{\"a\":1}\n{\"b\":", `..1.a`, `invalid json at offset 13`},
+	}
+	for _, tt := range tests {
+		res, err := GetE(tt.json, tt.path)
+		got := res.Raw
+		if err != nil {
+			got = err.Error()
+			assert(t, !res.Exists())
+		}
+		if got != tt.expect {
+			t.Fatalf("path '%v': expected '%v', got '%v'", tt.path, tt.expect, got)
+		}
+	}
+
+	_, err := GetE(json, "name.middle")
+	var ge *GetError
+	assert(t, errors.Is(err, ErrPathNotFound) && errors.As(err, &ge))
+	assert(t, ge.Resolved == "name" && ge.Component == "middle" && ge.Column == 6)
+	_, err = GetE(`[1,`, "0")
+	assert(t, errors.Is(err, ErrInvalidJSON) && errors.As(err, &ge) && ge.Offset == 3)
+	_, err = GetE(json, "name|@nope")
+	assert(t, errors.Is(err, ErrUnknownModifier))
+	_, err = GetE(json, "name.(")
+	assert(t, !errors.Is(err, ErrInvalidPath))
+	_, err = GetE(json, "#(")
+	assert(t, errors.Is(err, ErrInvalidPath))
+
+	_, err = GetE(json, "friends.-1.first", DisableNegativeIndex(true))
+	assert(t, errors.Is(err, ErrPathNotFound))
+	res, err := GetE(json, "friends.-1.first")
+	assert(t, err == nil && res.Str == "Jane")
+	res, err = GetE(`{"a.b":1}`, "a.b", WithRawPath(true))
+	assert(t, err == nil && res.Raw == "1")
+
+	r := NewModifierRegistry()
+	errBad := errors.New("bad")
+	r.AddE("bad", func(json string, arg Result) (string, error) { return "", errBad })
+	_, err = GetE(json, "name|@bad", WithModifiers(r))
+	var me *ModifierError
+	assert(t, errors.Is(err, errBad) && errors.As(err, &me) && me.Name == "bad")
+}
@@ -1,6 +1,8 @@
 package jj
 
 import (
+	jsongo "encoding/json"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -62,6 +64,8 @@ type Result struct {
 	// Indexes of all the elements that match on a path containing the '#'
 	// query character.
 	Indexes []int
+
+	useNumber bool // Value returns the numbers as json.Number
 }
 
 // String returns a string representation of the value.
@@ -295,6 +299,7 @@ func (t Result) ForEach(iterator func(key, value Result) bool) {
 		} else {
 			value.Index = s + t.Index
 		}
+		value.useNumber = t.useNumber
 		if !iterator(key, value) {
 			return
 		}
@@ -315,7 +320,13 @@ func (t Result) Map() map[string]Result {
 // Get searches result for the specified path.
 // The result should be a JSON array or object.
 func (t Result) Get(path string) Result {
-	r := Get(t.Raw, path)
+	return t.get(path, &PathOption{UseNumber: t.useNumber})
+}
+
+// get is Get with the option of the path that the result is a part of.
+func (t Result) get(path string, option *PathOption) Result {
+	r := getPath(t.Raw, path, option)
+	r.useNumber = option.UseNumber
 	if r.Indexes != nil {
 		for i := 0; i < len(r.Indexes); i++ {
 			r.Indexes[i] += t.Index
@@ -415,6 +426,7 @@ func (t Result) arrayOrMap(vc byte, valueize bool) (r arrayOrMapResult) {
 			value.Num = 0
 		}
 		value.Index = i + t.Index
+		value.useNumber = t.useNumber
 
 		i += len(value.Raw) - 1
 
@@ -462,8 +474,11 @@ end:
 // Invalid json will not panic, but it may return back unexpected results.
 // If you are consuming JSON from an unpredictable source then you may want to
 // use the Valid function first.
-func Parse(json string) Result {
+func Parse(json string, optionsFns ...PathOptionFn) Result {
 	var value Result
+	if len(optionsFns) > 0 {
+		value.useNumber = GetOptionFns(optionsFns).Apply(&PathOption{}).UseNumber
+	}
 	i := 0
 	for ; i < len(json); i++ {
 		if json[i] == '{' || json[i] == '[' {
@@ -511,8 +526,8 @@ func Parse(json string) Result {
 
 // ParseBytes parses the json and returns a result.
 // If working with bytes, this method preferred over Parse(string(data))
-func ParseBytes(json []byte) Result {
-	return Parse(string(json))
+func ParseBytes(json []byte, optionsFns ...PathOptionFn) Result {
+	return Parse(string(json), optionsFns...)
 }
 
 func squash(json string) string {
@@ -672,6 +687,12 @@ func (t Result) Value() interface{} {
 	case False:
 		return false
 	case Number:
+		if t.useNumber {
+			if len(t.Raw) == 0 {
+				return jsongo.Number(t.String())
+			}
+			return jsongo.Number(t.Raw)
+		}
 		return t.Num
 	case JSON:
 		r := t.arrayOrMap(0, true)
@@ -756,16 +777,38 @@ type arrayPathResult struct {
 	alogok  bool
 	arrch   bool
 	alogkey string
+	sliced  bool
+	slice   [3]int
+	sliceN  [3]bool // which slice parts are present
 	query   struct {
 		on    bool
 		all   bool
 		path  string
 		op    string
 		value string
+		expr  queryCond // set for the extended query syntax
 	}
 }
 
-func parseArrayPath(path string) (r arrayPathResult) {
+func parseArrayPath(path string, option *PathOption) (r arrayPathResult) {
+	if slice, sliceN, n, ok := parseSlice(path); ok {
+		// a slice gathers the elements like '#' does, and a following dot
+		// path is applied to each one of them.
+		r.part = path[:n]
+		r.arrch = true
+		r.sliced = true
+		r.slice, r.sliceN = slice, sliceN
+		r.alogok = true
+		if n < len(path) {
+			if path[n] == '|' || (n < len(path)-1 && isDotPiperChar(path[n+1:], option)) {
+				r.pipe = path[n+1:]
+				r.piped = true
+			} else {
+				r.alogkey = path[n+1:]
+			}
+		}
+		return
+	}
 	for i := 0; i < len(path); i++ {
 		if path[i] == '|' {
 			r.part = path[:i]
@@ -775,7 +818,7 @@ func parseArrayPath(path string) (r arrayPathResult) {
 		}
 		if path[i] == '.' {
 			r.part = path[:i]
-			if !r.arrch && i < len(path)-1 && isDotPiperChar(path[i+1:]) {
+			if !r.arrch && i < len(path)-1 && isDotPiperChar(path[i+1:], option) {
 				r.pipe = path[i+1:]
 				r.piped = true
 			} else {
@@ -809,6 +852,9 @@ func parseArrayPath(path string) (r arrayPathResult) {
 					r.query.path = qpath
 					r.query.op = op
 					r.query.value = value
+					if q := path[i+2 : fi-1]; isExtendedQuery(q) {
+						r.query.expr = cachedQueryExpr(q)
+					}
 
 					i = fi - 1
 					if i+1 < len(path) && path[i+1] == '#' {
@@ -824,6 +870,114 @@ func parseArrayPath(path string) (r arrayPathResult) {
 	return
 }
 
+// parseSlice parses a '[start:stop:step]' slice at the start of path, like
+// '[1:3]', '[-2:]' or '[::2]', and returns the length of it. Each part is an
+// optional, possibly negative, integer. The slice must be followed by the
+// end of the path, a '.' or a '|'.
+func parseSlice(path string) (parts [3]int, present [3]bool, n int, ok bool) {
+	if len(path) < 3 || path[0] != '[' {
+		return parts, present, 0, false
+	}
+	var part int
+	for i := 1; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == ' ':
+		case c == ':':
+			if part == 2 {
+				return parts, present, 0, false
+			}
+			part++
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(path) && path[j] >= '0' && path[j] <= '9' {
+				j++
+			}
+			v, err := strconv.Atoi(path[i:j])
+			if err != nil || present[part] {
+				return parts, present, 0, false
+			}
+			parts[part], present[part] = v, true
+			i = j - 1
+		case c == ']':
+			n = i + 1
+			if part == 0 || (n < len(path) && path[n] != '.' && path[n] != '|') {
+				return parts, present, 0, false
+			}
+			return parts, present, n, true
+		default:
+			return parts, present, 0, false
+		}
+	}
+	return parts, present, 0, false
+}
+
+func isSlice(path string) bool {
+	_, _, _, ok := parseSlice(path)
+	return ok
+}
+
+// sliceBounds computes the bounds of a start:end:step slice over n elements
+// following the normalization rules of RFC 9535. The parts which are not
+// present get their defaults.
+func sliceBounds(n int, parts [3]int, present [3]bool) (start, end, step int) {
+	step = 1
+	if present[2] {
+		step = parts[2]
+	}
+	if step == 0 {
+		return 0, 0, 0
+	}
+	normalize := func(i int) int {
+		if i < 0 {
+			return n + i
+		}
+		return i
+	}
+	clamp := func(i, lo, hi int) int {
+		if i < lo {
+			return lo
+		}
+		if i > hi {
+			return hi
+		}
+		return i
+	}
+	if step > 0 {
+		start, end = 0, n
+		if present[0] {
+			start = clamp(normalize(parts[0]), 0, n)
+		}
+		if present[1] {
+			end = clamp(normalize(parts[1]), 0, n)
+		}
+		return start, end, step
+	}
+	start, end = n-1, -1
+	if present[0] {
+		start = clamp(normalize(parts[0]), -1, n-1)
+	}
+	if present[1] {
+		end = clamp(normalize(parts[1]), -1, n-1)
+	}
+	return start, end, step
+}
+
+// sliceItems picks the items of a start:end:step slice.
+func sliceItems(items []int, parts [3]int, present [3]bool) []int {
+	start, end, step := sliceBounds(len(items), parts, present)
+	var picked []int
+	if step > 0 {
+		for i := start; i < end; i += step {
+			picked = append(picked, items[i])
+		}
+	} else if step < 0 {
+		for i := start; i > end; i += step {
+			picked = append(picked, items[i])
+		}
+	}
+	return picked
+}
+
 // splitQuery takes a query and splits it into three parts:
 //
 //	path, op, middle, and right.
@@ -944,7 +1098,7 @@ right:
 }
 
 // peek at the next byte and see if it's a '@', '[', or '{'.
-func isDotPiperChar(s string) bool {
+func isDotPiperChar(s string, option *PathOption) bool {
 	if DisableModifiers {
 		return false
 	}
@@ -957,10 +1111,9 @@ func isDotPiperChar(s string) bool {
 				break
 			}
 		}
-		_, ok := modifiers[s[1:i]]
-		return ok
+		return option.modifiers().Exists(s[1:i])
 	}
-	return c == '[' || c == '{'
+	return (c == '[' && !isSlice(s)) || c == '{'
 }
 
 type objectPathResult struct {
@@ -987,7 +1140,7 @@ func parseObjectPath(path string, option *PathOption) (r objectPathResult) {
 		}
 		if path[i] == '.' {
 			r.part = path[:i]
-			if i < len(path)-1 && isDotPiperChar(path[i+1:]) {
+			if i < len(path)-1 && isDotPiperChar(path[i+1:], option) {
 				r.pipe = path[i+1:]
 				r.piped = true
 			} else {
@@ -1017,7 +1170,7 @@ func parseObjectPath(path string, option *PathOption) (r objectPathResult) {
 						continue
 					} else if path[i] == '.' {
 						r.part = string(epart)
-						if i < len(path)-1 && isDotPiperChar(path[i+1:]) {
+						if i < len(path)-1 && isDotPiperChar(path[i+1:], option) {
 							r.pipe = path[i+1:]
 							r.piped = true
 						} else {
@@ -1093,13 +1246,22 @@ func parseSquash(json string, i int) (int, string) {
 	return i, json[s:]
 }
 
-func parseObject(c *parseContext, i int, path string, option *PathOption) (int, bool) {
+func parseObject(c *parseContext, i int, path string, st *pathStep, option *PathOption) (int, bool) {
 	var pmatch, kesc, vesc, ok, hit bool
 	var key, val string
-	rp := parseObjectPath(path, option)
+	var rp objectPathResult
+	if st != nil {
+		rp = st.obj
+	} else {
+		rp = parseObjectPath(path, option)
+	}
 	if !rp.more && rp.piped {
 		c.pipe = rp.pipe
 		c.piped = true
+		c.pipePath = st.objPipePath()
+	}
+	if rp.wild && rp.part == "**" {
+		return parseDeep(c, i-1, false, rp.more, rp.path, st, option)
 	}
 	for i < len(c.json) {
 		for ; i < len(c.json); i++ {
@@ -1192,7 +1354,7 @@ func parseObject(c *parseContext, i int, path string, option *PathOption) (int,
 				}
 			case '{':
 				if pmatch && !hit {
-					i, hit = parseObject(c, i+1, rp.path, option)
+					i, hit = parseObject(c, i+1, rp.path, st.objNext(), option)
 					if hit {
 						return i, true
 					}
@@ -1206,7 +1368,7 @@ func parseObject(c *parseContext, i int, path string, option *PathOption) (int,
 				}
 			case '[':
 				if pmatch && !hit {
-					i, hit = parseArray(c, i+1, rp.path, option)
+					i, hit = parseArray(c, i+1, rp.path, st.objNext(), option)
 					if hit {
 						return i, true
 					}
@@ -1265,7 +1427,12 @@ func matchLimit(str, pattern string) bool {
 }
 
 func queryMatches(rp *arrayPathResult, value Result) bool {
-	rpv := rp.query.value
+	return compareQuery(rp.query.op, rp.query.value, value)
+}
+
+// compareQuery compares a value with the value of a query, like the 30 of
+// '#(age>30)'. An empty op tests for existence.
+func compareQuery(op, rpv string, value Result) bool {
 	if len(rpv) > 0 && rpv[0] == '~' {
 		// convert to bool
 		rpv = rpv[1:]
@@ -1278,7 +1445,7 @@ func queryMatches(rp *arrayPathResult, value Result) bool {
 	if !value.Exists() {
 		return false
 	}
-	if rp.query.op == "" {
+	if op == "" {
 		// the query is only looking for existence, such as:
 		//   friends.#(name)
 		// which makes sure that the array "friends" has an element of
@@ -1287,7 +1454,7 @@ func queryMatches(rp *arrayPathResult, value Result) bool {
 	}
 	switch value.Type {
 	case String:
-		switch rp.query.op {
+		switch op {
 		case "=":
 			return value.Str == rpv
 		case "!=":
@@ -1307,7 +1474,7 @@ func queryMatches(rp *arrayPathResult, value Result) bool {
 		}
 	case Number:
 		rpvn, _ := strconv.ParseFloat(rpv, 64)
-		switch rp.query.op {
+		switch op {
 		case "=":
 			return value.Num == rpvn
 		case "!=":
@@ -1322,7 +1489,7 @@ func queryMatches(rp *arrayPathResult, value Result) bool {
 			return value.Num >= rpvn
 		}
 	case True:
-		switch rp.query.op {
+		switch op {
 		case "=":
 			return rpv == "true"
 		case "!=":
@@ -1333,7 +1500,7 @@ func queryMatches(rp *arrayPathResult, value Result) bool {
 			return true
 		}
 	case False:
-		switch rp.query.op {
+		switch op {
 		case "=":
 			return rpv == "false"
 		case "!=":
@@ -1347,7 +1514,7 @@ func queryMatches(rp *arrayPathResult, value Result) bool {
 	return false
 }
 
-func parseArray(c *parseContext, i int, path string, option *PathOption) (int, bool) {
+func parseArray(c *parseContext, i int, path string, st *pathStep, option *PathOption) (int, bool) {
 	var pmatch, vesc, ok, hit bool
 	var val string
 	var h int
@@ -1356,9 +1523,14 @@ func parseArray(c *parseContext, i int, path string, option *PathOption) (int, b
 	var partidxOk bool
 	var multires []byte
 	var queryIndexes []int
-	rp := parseArrayPath(path)
+	var rp arrayPathResult
+	if st != nil {
+		rp = st.arr
+	} else {
+		rp = parseArrayPath(path, option)
+	}
 	if !rp.arrch {
-		n, ok := parseUint(rp.part)
+		n, ok := parseInt(rp.part)
 		if !ok {
 			partidx = -1
 		} else {
@@ -1369,6 +1541,14 @@ func parseArray(c *parseContext, i int, path string, option *PathOption) (int, b
 	if !rp.more && rp.piped {
 		c.pipe = rp.pipe
 		c.piped = true
+		c.pipePath = st.arrPipePath()
+	}
+	if !rp.arrch && rp.part == "**" && !option.RawPath {
+		if c.lines && i == 0 {
+			// the JSON Lines document itself, which has no '['
+			return parseDeep(c, 0, true, rp.more, rp.path, st, option)
+		}
+		return parseDeep(c, i-1, false, rp.more, rp.path, st, option)
 	}
 
 	procQuery := func(qval Result) bool {
@@ -1382,23 +1562,40 @@ func parseArray(c *parseContext, i int, path string, option *PathOption) (int, b
 		fillIndex(c.json, &tmp)
 		parentIndex := tmp.value.Index
 		var res Result
-		if qval.Type == JSON {
-			res = qval.Get(rp.query.path)
+		var match bool
+		if rp.query.expr != nil {
+			match = rp.query.expr.match(qval)
 		} else {
-			if rp.query.path != "" {
-				return false
+			if qval.Type == JSON {
+				if st != nil {
+					res = st.query.getResult(qval, c.modErr)
+				} else {
+					res = qval.get(rp.query.path, option.nested())
+				}
+			} else {
+				if rp.query.path != "" {
+					return false
+				}
+				res = qval
 			}
-			res = qval
+			match = queryMatches(&rp, res)
 		}
-		if queryMatches(&rp, res) {
-			if rp.more {
+		if match {
+			if rp.more && st != nil {
+				if st.qpiped {
+					c.pipe = st.qpipe.path
+					c.piped = true
+					c.pipePath = st.qpipe
+				}
+				res = st.qmore.getResult(qval, c.modErr)
+			} else if rp.more {
 				left, right, ok := splitPossiblePipe(rp.path)
 				if ok {
 					rp.path = left
 					c.pipe = right
 					c.piped = true
 				}
-				res = qval.Get(rp.path)
+				res = qval.get(rp.path, option.nested())
 			} else {
 				res = qval
 			}
@@ -1481,7 +1678,7 @@ LOOP:
 				}
 			case '{':
 				if pmatch && !hit {
-					i, hit = parseObject(c, i+1, rp.path, option)
+					i, hit = parseObject(c, i+1, rp.path, st.arrNext(), option)
 					if hit {
 						if rp.alogok {
 							break
@@ -1505,7 +1702,7 @@ LOOP:
 				}
 			case '[':
 				if pmatch && !hit {
-					i, hit = parseArray(c, i+1, rp.path, option)
+					i, hit = parseArray(c, i+1, rp.path, st.arrNext(), option)
 					if hit {
 						if rp.alogok {
 							break
@@ -1565,13 +1762,26 @@ LOOP:
 				'i', 'I', 'N':
 				num = true
 			case ']':
-				if rp.arrch && rp.part == "#" {
+				if rp.arrch && (rp.part == "#" || rp.sliced) {
 					if rp.alogok {
-						left, right, ok := splitPossiblePipe(rp.alogkey)
-						if ok {
-							rp.alogkey = left
-							c.pipe = right
-							c.piped = true
+						var alogPath *Path
+						if st != nil {
+							alogPath = st.alog
+							if st.alogPiped {
+								c.pipe = st.alogPipe.path
+								c.piped = true
+								c.pipePath = st.alogPipe
+							}
+						} else {
+							left, right, ok := splitPossiblePipe(rp.alogkey)
+							if ok {
+								rp.alogkey = left
+								c.pipe = right
+								c.piped = true
+							}
+						}
+						if rp.sliced {
+							alog = sliceItems(alog[:h-1], rp.slice, rp.sliceN)
 						}
 						indexes := make([]int, 0, 64)
 						jsons := make([]byte, 0, 64)
@@ -1589,7 +1799,16 @@ LOOP:
 							if idx < len(c.json) && c.json[idx] != ']' {
 								_, res, ok := parseAny(c.json, idx, true)
 								if ok {
-									res := res.Get(rp.alogkey)
+									if alogPath != nil {
+										res = alogPath.getResult(res, c.modErr)
+									} else if !rp.sliced || rp.alogkey != "" {
+										res = res.get(rp.alogkey, option.nested())
+									} else {
+										var tmp parseContext
+										tmp.value = res
+										fillIndex(c.json, &tmp)
+										res = tmp.value
+									}
 									if res.Exists() {
 										if k > 0 {
 											jsons = append(jsons, ',')
@@ -1672,6 +1891,100 @@ LOOP:
 	return i, false
 }
 
+// parseDeep handles the '**' recursive descent component. The path following
+// it is searched for in the value at i and in every value nested within it,
+// and the results are gathered into an array, in document order.
+// A trailing '**' gathers all of the nested values.
+func parseDeep(c *parseContext, i int, lines, more bool, path string, st *pathStep, option *PathOption) (int, bool) {
+	var roots []Result
+	if lines {
+		for i < len(c.json) {
+			var res Result
+			var ok bool
+			i, res, ok = parseAny(c.json, i, true)
+			if !ok {
+				break
+			}
+			roots = append(roots, res)
+		}
+	} else {
+		var res Result
+		i, res, _ = parseAny(c.json, i, true)
+		roots = append(roots, res)
+	}
+	var deepPath *Path
+	if more {
+		if st != nil {
+			deepPath = st.deep
+			if st.deepPiped {
+				c.pipe = st.deepPipe.path
+				c.piped = true
+				c.pipePath = st.deepPipe
+			}
+		} else if left, right, ok := splitPossiblePipe(path); ok {
+			path = left
+			c.pipe = right
+			c.piped = true
+		}
+	}
+	indexes := make([]int, 0, 64)
+	jsons := make([]byte, 0, 64)
+	jsons = append(jsons, '[')
+	add := func(res Result) {
+		if len(indexes) > 0 {
+			jsons = append(jsons, ',')
+		}
+		raw := res.Raw
+		if len(raw) == 0 {
+			raw = res.String()
+		}
+		jsons = append(jsons, raw...)
+		indexes = append(indexes, res.Index)
+	}
+	for _, root := range roots {
+		switch {
+		case more:
+			descend(root, func(node Result) {
+				if !node.IsJSON() {
+					return
+				}
+				var res Result
+				if deepPath != nil {
+					res = deepPath.getResult(node, c.modErr)
+				} else {
+					res = node.get(path, option.nested())
+				}
+				if res.Exists() {
+					add(res)
+				}
+			})
+		case lines:
+			descend(root, add)
+		default:
+			root.ForEach(func(_, value Result) bool {
+				descend(value, add)
+				return true
+			})
+		}
+	}
+	jsons = append(jsons, ']')
+	c.value.Type = JSON
+	c.value.Raw = string(jsons)
+	c.value.Indexes = indexes
+	return i, true
+}
+
+// descend visits the node and all of its descendants in document order.
+func descend(node Result, visit func(n Result)) {
+	visit(node)
+	if node.IsJSON() {
+		node.ForEach(func(_, value Result) bool {
+			descend(value, visit)
+			return true
+		})
+	}
+}
+
 func splitPossiblePipe(path string) (left, right string, ok bool) {
 	// take a quick peek for the pipe character. If found we'll split the piped
 	// part of the path into the c.pipe field and shorten the rp.
@@ -1933,12 +2246,14 @@ func AppendJSONString(dst []byte, s string) []byte {
 }
 
 type parseContext struct {
-	json  string
-	value Result
-	pipe  string
-	piped bool
-	calcd bool
-	lines bool
+	json     string
+	value    Result
+	pipe     string
+	pipePath *Path  // compiled pipe, set only when evaluating a compiled Path
+	modErr   *error // the first error of a modifier of a compiled Path
+	piped    bool
+	calcd    bool
+	lines    bool
 }
 
 // PathOption defines the options to the Get method.
@@ -1947,6 +2262,14 @@ type PathOption struct {
 	RawPath bool
 	// DisableNegativeIndex disables the negative index support in jj.Get.
 	DisableNegativeIndex bool
+	// UseNumber makes Result.Value return the numbers as json.Number, which
+	// keeps all of their digits, like json.Decoder.UseNumber.
+	UseNumber bool
+	// Modifiers is the registry of the modifiers of the path, which are
+	// the DefaultModifiers when it is nil.
+	Modifiers *ModifierRegistry
+
+	modErr *error // the first error of a modifier, for ModifierRegistry.Get
 }
 
 // PathOptionFn is the proto type of function option.
@@ -1966,6 +2289,13 @@ func WithRawPath(v bool) PathOptionFn {
 	}
 }
 
+// WithUseNumber set the options UseNumber.
+func WithUseNumber(v bool) PathOptionFn {
+	return func(o *PathOption) {
+		o.UseNumber = v
+	}
+}
+
 // ApplyGetOption set the options RawPath.
 func ApplyGetOption(v PathOption) PathOptionFn {
 	return func(o *PathOption) {
@@ -1993,6 +2323,7 @@ func (fns GetOptionFns) Apply(o *PathOption) *PathOption {
 // To access an array value use the index as the key.
 // To get the number of elements in an array or to access a child path, use
 // the '#' character.
+// The '**' key searches for the rest of the path at any depth.
 // The dot and wildcard character can be escaped with '\'.
 //
 //	{
@@ -2012,13 +2343,25 @@ func (fns GetOptionFns) Apply(o *PathOption) *PathOption {
 //	"child*.2"           >> "Jack"
 //	"c?ildren.0"         >> "Sara"
 //	"friends.#.first"    >> ["James","Roger"]
+//	"**.first"           >> ["Tom","James","Roger"]
 //
 // This function expects that the json is well-formed, and does not validate.
 // Invalid json will not panic, but it may return back unexpected results.
 // If you are consuming JSON from an unpredictable source then you may want to
 // use the Valid function first.
 func Get(json, path string, optionsFns ...PathOptionFn) Result {
-	option := GetOptionFns(optionsFns).Apply(&PathOption{})
+	// the option stays on the stack when there are no option functions,
+	// which would make it escape
+	var option PathOption
+	if len(optionsFns) > 0 {
+		option = *GetOptionFns(optionsFns).Apply(&PathOption{})
+	}
+	res := getPath(json, path, &option)
+	res.useNumber = option.UseNumber
+	return res
+}
+
+func getPath(json, path string, option *PathOption) Result {
 	if !option.RawPath && len(path) > 1 {
 		if (path[0] == '@' && !DisableModifiers) || path[0] == '!' {
 			// possible modifier
@@ -2026,14 +2369,14 @@ func Get(json, path string, optionsFns ...PathOptionFn) Result {
 			var npath string
 			var rjson string
 			if path[0] == '@' && !DisableModifiers {
-				npath, rjson, ok = execModifier(json, path)
+				npath, rjson, ok = execModifier(json, path, option)
 			} else if path[0] == '!' {
 				npath, rjson, ok = execStatic(json, path)
 			}
 			if ok {
 				path = npath
 				if len(path) > 0 && (path[0] == '|' || path[0] == '.') {
-					res := Get(rjson, path[1:])
+					res := getPath(rjson, path[1:], option.nested())
 					res.Index = 0
 					res.Indexes = nil
 					return res
@@ -2041,7 +2384,7 @@ func Get(json, path string, optionsFns ...PathOptionFn) Result {
 				return Parse(rjson)
 			}
 		}
-		if path[0] == '[' || path[0] == '{' {
+		if (path[0] == '[' && !isSlice(path)) || path[0] == '{' {
 			// using a subselector path
 			kind := path[0]
 			var ok bool
@@ -2053,7 +2396,7 @@ func Get(json, path string, optionsFns ...PathOptionFn) Result {
 					b = append(b, kind)
 					var i int
 					for _, sub := range subs {
-						res := Get(json, sub.path)
+						res := getPath(json, sub.path, option.nested())
 						if res.Exists() {
 							if i > 0 {
 								b = append(b, ',')
@@ -2093,7 +2436,7 @@ func Get(json, path string, optionsFns ...PathOptionFn) Result {
 					res.Raw = string(b)
 					res.Type = JSON
 					if len(path) > 0 {
-						res = res.Get(path[1:])
+						res = res.get(path[1:], option.nested())
 					}
 					res.Index = 0
 					return res
@@ -2105,23 +2448,23 @@ func Get(json, path string, optionsFns ...PathOptionFn) Result {
 	c := &parseContext{json: json}
 	if !option.RawPath && len(path) >= 2 && path[0] == '.' && path[1] == '.' {
 		c.lines = true
-		parseArray(c, 0, path[2:], option)
+		parseArray(c, 0, path[2:], nil, option)
 	} else {
 		for ; i < len(c.json); i++ {
 			if c.json[i] == '{' {
 				i++
-				parseObject(c, i, path, option)
+				parseObject(c, i, path, nil, option)
 				break
 			}
 			if c.json[i] == '[' {
 				i++
-				parseArray(c, i, path, option)
+				parseArray(c, i, path, nil, option)
 				break
 			}
 		}
 	}
 	if c.piped {
-		res := c.value.Get(c.pipe)
+		res := c.value.get(c.pipe, option.nested())
 		res.Index = 0
 		return res
 	}
@@ -2319,8 +2662,8 @@ func parseAny(json string, i int, hit bool) (int, Result, bool) {
 				case 'f':
 					res.Type = False
 				}
-				return i, res, true
 			}
+			return i, res, true
 		case '+', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9',
 			'i', 'I', 'N':
 			num = true
@@ -2342,25 +2685,303 @@ func parseAny(json string, i int, hit bool) (int, Result, bool) {
 // GetMany searches json for the multiple paths.
 // The return value is a Result array where the number of items
 // will be equal to the number of input paths.
+//
+// When there are many paths, their leading keys and indexes, like
+// "name.first" and "friends.1", are merged into a trie, so that the json is
+// walked only once for all of them. The rest of a path, like the "#.first"
+// of "friends.#.first", is searched for in the value that the keys lead to.
 func GetMany(json string, path ...string) []Result {
 	res := make([]Result, len(path))
+	if len(path) < manyTrieMinPaths {
+		for i, path := range path {
+			res[i] = Get(json, path)
+		}
+		return res
+	}
+	m := manyTrie{
+		nodes: make([]manyNode, 1, len(path)+1),
+		rests: make([]string, len(path)),
+		next:  make([]int, len(path)),
+		ends:  make([]int, len(path)),
+	}
+	m.nodes[0] = manyNode{index: -1, path: -1}
+	var retry []int
 	for i, path := range path {
-		res[i] = Get(json, path)
+		if !m.add(i, path) {
+			// a modifier, a multipath or a '..' path of JSON Lines
+			res[i] = Get(json, path)
+		}
+	}
+	if len(m.nodes) > 1 {
+		var i int
+		for ; i < len(json) && json[i] <= ' '; i++ {
+		}
+		if i < len(json) {
+			_, retry = m.walk(0, json, i, false, res, retry)
+		}
+	}
+	for _, i := range retry {
+		res[i] = Get(json, path[i])
 	}
 	return res
 }
 
+// manyTrieMinPaths is the number of paths from which GetMany walks the json
+// with a trie. Fewer paths are searched for one by one, which is faster.
+const manyTrieMinPaths = 16
+
 // GetManyBytes searches json for the multiple paths.
 // The return value is a Result array where the number of items
 // will be equal to the number of input paths.
 func GetManyBytes(json []byte, path ...string) []Result {
-	res := make([]Result, len(path))
-	for i, path := range path {
-		res[i] = GetBytes(json, path)
+	if json == nil {
+		return GetMany("", path...)
+	}
+	res := GetMany(*(*string)(unsafe.Pointer(&json)), path...)
+	for i := range res {
+		res[i] = getBytesWith(json, func(string) Result { return res[i] })
 	}
 	return res
 }
 
+// manyTrie is the trie of the keys of the GetMany paths. The nodes link to
+// each other by their position in the nodes, the root is the first one.
+type manyTrie struct {
+	nodes []manyNode
+	rests []string // the rest of each path after its keys
+	next  []int    // the next path ending at the same node, or -1
+	ends  []int    // the node where each path ends, or -1 once found
+}
+
+type manyNode struct {
+	key     string
+	index   int // the key as an array index, or -1
+	parent  int
+	child   int // the first child, or 0
+	sibling int // the next child of the parent, or 0
+	path    int // the first path ending at the node, or -1
+	// pending is the number of the paths ending at the node or below it
+	// that are not found yet. Like Get, the walk goes on to the next ones
+	// of duplicate keys until the paths are found.
+	pending int
+}
+
+// add adds the keys of the path i to the trie. It returns false when the
+// path does not start with a plain key.
+func (m *manyTrie) add(i int, path string) bool {
+	var n int
+	var rest string
+	for j := 0; j < len(path); {
+		key, sep, ok := nextSimpleComp(path, j)
+		if !ok || (len(key) > 1 && key[0] == '-' && isnum(key[1])) {
+			// a negative index needs the length of the array
+			rest = path[j:]
+			break
+		}
+		n = m.child(n, key, keyIndex(key))
+		if sep == len(path) {
+			break
+		}
+		if path[sep] == '|' {
+			// Get does not look for the pipe in later duplicate keys
+			rest = path[sep:]
+			break
+		}
+		if j = sep + 1; j == len(path) {
+			rest = path[sep:]
+		}
+	}
+	if n == 0 {
+		return false
+	}
+	m.rests[i] = rest
+	m.next[i] = m.nodes[n].path
+	m.ends[i] = n
+	m.nodes[n].path = i
+	for ; n >= 0; n = m.nodes[n].parent {
+		m.nodes[n].pending++
+		if n == 0 {
+			break
+		}
+	}
+	return true
+}
+
+// keyIndex returns the key as an array index, or -1 when it is not one.
+func keyIndex(key string) int {
+	if key == "" || len(key) > 18 {
+		return -1
+	}
+	var index int
+	for i := 0; i < len(key); i++ {
+		if !isnum(key[i]) {
+			return -1
+		}
+		index = index*10 + int(key[i]-'0')
+	}
+	return index
+}
+
+// child returns the child of the node n for the key, adding it if needed.
+func (m *manyTrie) child(n int, key string, index int) int {
+	last := 0
+	for c := m.nodes[n].child; c != 0; c = m.nodes[c].sibling {
+		if m.nodes[c].key == key {
+			return c
+		}
+		last = c
+	}
+	m.nodes = append(m.nodes, manyNode{key: key, index: index, parent: n, path: -1})
+	c := len(m.nodes) - 1
+	if last == 0 {
+		m.nodes[n].child = c
+	} else {
+		m.nodes[last].sibling = c
+	}
+	return c
+}
+
+// found marks the path i as found, for the nodes it goes through.
+func (m *manyTrie) found(i int) {
+	for n := m.ends[i]; ; n = m.nodes[n].parent {
+		m.nodes[n].pending--
+		if n == 0 {
+			break
+		}
+	}
+	m.ends[i] = -1
+}
+
+// walk walks the value at i of the json for the node n, setting the results
+// of the paths of the node and of its children. It returns the end of the
+// value, which is only known when needEnd is true, or -1 for invalid json.
+// The paths that must be searched for with Get are added to retry.
+func (m *manyTrie) walk(n int, json string, i int, needEnd bool, res []Result, retry []int) (int, []int) {
+	if m.nodes[n].path >= 0 {
+		end, value, ok := parseAny(json, i, true)
+		if !ok {
+			return -1, retry
+		}
+		value.Index = i
+		retry = m.set(n, value, res, retry)
+		if m.nodes[n].pending > 0 && value.Type == JSON {
+			_, retry = m.walkChildren(n, json, i, false, res, retry)
+		}
+		return end, retry
+	}
+	if json[i] != '{' && json[i] != '[' {
+		end, _, ok := parseAny(json, i, false)
+		if !ok {
+			return -1, retry
+		}
+		return end, retry
+	}
+	return m.walkChildren(n, json, i, needEnd, res, retry)
+}
+
+// set sets the results of the paths ending at the node n that are not found
+// yet. The paths with a rest that is missing in the value are added to
+// retry, because Get may find them in the values of later duplicate keys.
+func (m *manyTrie) set(n int, value Result, res []Result, retry []int) []int {
+	for p := m.nodes[n].path; p >= 0; p = m.next[p] {
+		if m.ends[p] < 0 {
+			continue
+		}
+		m.found(p)
+		rest := m.rests[p]
+		switch {
+		case rest == "":
+			res[p] = value
+		case rest[0] == '|':
+			// like Get, the values after a pipe have no index
+			res[p] = Get(value.Raw, rest[1:])
+			res[p].Index = 0
+		default:
+			// like value.Get, but an Index of zero is an unknown position
+			res[p] = Get(value.Raw, rest)
+			if res[p].Indexes != nil {
+				for j := range res[p].Indexes {
+					res[p].Indexes[j] += value.Index
+				}
+			} else if res[p].Index > 0 {
+				res[p].Index += value.Index
+			}
+		}
+		if !res[p].Exists() {
+			retry = append(retry, p)
+		}
+	}
+	return retry
+}
+
+// walkChildren walks the members or elements of the object or array at i
+// for the children of the node n. See walk.
+func (m *manyTrie) walkChildren(n int, json string, i int, needEnd bool, res []Result, retry []int) (int, []int) {
+	obj := json[i] == '{'
+	for index := 0; ; index++ {
+		for i++; i < len(json) && (json[i] <= ' ' || json[i] == ','); i++ {
+		}
+		if i >= len(json) {
+			return -1, retry
+		}
+		if json[i] == '}' || json[i] == ']' {
+			return i + 1, retry
+		}
+		if m.nodes[n].pending == 0 {
+			if !needEnd {
+				return -1, retry
+			}
+			// the character before i is skipped as the opening one
+			end, _ := parseSquash(json, i-1)
+			return end, retry
+		}
+		var key string
+		if obj {
+			var vesc, ok bool
+			if json[i] != '"' {
+				return -1, retry
+			}
+			if i, key, vesc, ok = parseString(json, i+1); !ok {
+				return -1, retry
+			}
+			if key = key[1 : len(key)-1]; vesc {
+				key = unescape(key)
+			}
+			for ; i < len(json) && (json[i] <= ' ' || json[i] == ':'); i++ {
+			}
+			if i == len(json) {
+				return -1, retry
+			}
+		}
+		end := -1
+		var hit bool
+		for c := m.nodes[n].child; c != 0; c = m.nodes[c].sibling {
+			if m.nodes[c].pending == 0 || (obj && m.nodes[c].key != key) ||
+				(!obj && m.nodes[c].index != index) {
+				continue
+			}
+			hit = true
+			// the end is needed to go on with the next members, unless the
+			// child finds all the paths left
+			more := needEnd || m.nodes[n].pending > m.nodes[c].pending
+			end, retry = m.walk(c, json, i, more, res, retry)
+		}
+		if !hit {
+			var ok bool
+			if end, _, ok = parseAny(json, i, false); !ok {
+				return -1, retry
+			}
+		}
+		if m.nodes[n].pending == 0 && !needEnd {
+			return -1, retry
+		}
+		if end < 0 {
+			return -1, retry
+		}
+		i = end - 1
+	}
+}
+
 // ValidPayload validates payload.
 func ValidPayload(data []byte, i int) (typ Type, outi int, ok bool) {
 	for ; i < len(data); i++ {
@@ -2755,8 +3376,32 @@ func execStatic(json, path string) (pathOut, res string, ok bool) {
 
 // execModifier parses the path to find a matching modifier function.
 // The input expects that the path already starts with a '@'
-func execModifier(json, path string) (pathOut, res string, ok bool) {
-	name := path[1:]
+func execModifier(json, path string, option *PathOption) (pathOut, res string, ok bool) {
+	mods := option.modifiers()
+	name, args, pathOut := parseModifier(path, mods)
+	if m, ok := mods.lookup(name); ok {
+		res, err := m.exec(json, args)
+		if err != nil {
+			modifierFailed(option.modErr, name, err)
+			res = ""
+		}
+		return pathOut, res, true
+	}
+	return pathOut, res, false
+}
+
+// modifierFailed records the error of a modifier in modErr, when it is not
+// nil and has no error yet.
+func modifierFailed(modErr *error, name string, err error) {
+	if modErr != nil && *modErr == nil {
+		*modErr = &ModifierError{Name: name, Err: err}
+	}
+}
+
+// parseModifier splits a modifier path, which already starts with a '@',
+// into the modifier name, its arguments and the remaining path.
+func parseModifier(path string, mods *ModifierRegistry) (name, args, pathOut string) {
+	name = path[1:]
 	var hasArgs bool
 	for i := 1; i < len(path); i++ {
 		if path[i] == ':' {
@@ -2776,33 +3421,29 @@ func execModifier(json, path string) (pathOut, res string, ok bool) {
 			break
 		}
 	}
-	if fn, ok := modifiers[name]; ok {
-		var args string
-		if hasArgs {
-			var parsedArgs bool
-			switch pathOut[0] {
-			case '{', '[', '"':
-				res := Parse(pathOut)
-				if res.Exists() {
-					args = squash(pathOut)
-					pathOut = pathOut[len(args):]
-					parsedArgs = true
-				}
+	if hasArgs && mods.Exists(name) {
+		var parsedArgs bool
+		switch pathOut[0] {
+		case '{', '[', '"':
+			res := Parse(pathOut)
+			if res.Exists() {
+				args = squash(pathOut)
+				pathOut = pathOut[len(args):]
+				parsedArgs = true
 			}
-			if !parsedArgs {
-				idx := strings.IndexByte(pathOut, '|')
-				if idx == -1 {
-					args = pathOut
-					pathOut = ""
-				} else {
-					args = pathOut[:idx]
-					pathOut = pathOut[idx:]
-				}
+		}
+		if !parsedArgs {
+			idx := strings.IndexByte(pathOut, '|')
+			if idx == -1 {
+				args = pathOut
+				pathOut = ""
+			} else {
+				args = pathOut[:idx]
+				pathOut = pathOut[idx:]
 			}
 		}
-		return pathOut, fn(json, args), true
 	}
-	return pathOut, res, false
+	return name, args, pathOut
 }
 
 // unwrap removes the '[]' or '{}' characters around json
@@ -2817,32 +3458,46 @@ func unwrap(json string) string {
 // DisableModifiers will disable the modifier syntax
 var DisableModifiers = false
 
-var modifiers = map[string]func(json, arg string) string{
-	"pretty":  modPretty,
-	"ugly":    modUgly,
-	"reverse": modReverse,
-	"this":    modThis,
-	"flatten": modFlatten,
-	"join":    modJoin,
-	"valid":   modValid,
-	"keys":    modKeys,
-	"values":  modValues,
-	"tostr":   modToStr,
-	"fromstr": modFromStr,
-	"group":   modGroup,
-}
-
-// AddModifier binds a custom modifier command to the GJSON syntax.
-// This operation is not thread safe and should be executed prior to
-// using all other gjson function.
+func init() {
+	DefaultModifiers.mods.Store(builtinModifiers())
+}
+
+// builtinModifiers returns the built-in modifiers.
+func builtinModifiers() map[string]modifier {
+	return map[string]modifier{
+		"pretty":   {fn: modPretty},
+		"ugly":     {fn: modUgly},
+		"reverse":  {fn: modReverse},
+		"this":     {fn: modThis},
+		"flatten":  {fn: modFlatten},
+		"join":     {fn: modJoin},
+		"valid":    {fn: modValid},
+		"keys":     {fn: modKeys},
+		"values":   {fn: modValues},
+		"tostr":    {fn: modToStr},
+		"fromstr":  {fn: modFromStr},
+		"group":    {fn: modGroup},
+		"sum":      {fn: modSum},
+		"avg":      {fn: modAvg},
+		"min":      {fn: modMin},
+		"max":      {fn: modMax},
+		"count":    {fn: modCount},
+		"distinct": {fn: modDistinct},
+		"sort":     {fn: modSort},
+		"time":     {fn: modTime},
+	}
+}
+
+// AddModifier binds a custom modifier command to the GJSON syntax of the
+// DefaultModifiers.
 func AddModifier(name string, fn func(json, arg string) string) {
-	modifiers[name] = fn
+	DefaultModifiers.Add(name, fn)
 }
 
-// ModifierExists returns true when the specified modifier exists.
+// ModifierExists returns true when the specified modifier exists in the
+// DefaultModifiers.
 func ModifierExists(name string, fn func(json, arg string) string) bool {
-	_, ok := modifiers[name]
-	return ok
+	return DefaultModifiers.Exists(name)
 }
 
 // cleanWS remove any non-whitespace from string
@@ -3179,6 +3834,223 @@ func modGroup(json, arg string) string {
 	return string(data)
 }
 
+// aggArgs are the arguments of the aggregation modifiers. The arg is either
+// a path, quoted or not, like "price", or an object like
+//
+//	{"key":"price","desc":true}
+type aggArgs struct {
+	key  string // sub-path applied to each of the values
+	desc bool   // descending order, only for @sort
+}
+
+func parseAggArgs(arg string) (a aggArgs) {
+	arg = trim(arg)
+	if arg == "" {
+		return a
+	}
+	switch res := Parse(arg); res.Type {
+	case String:
+		a.key = res.Str
+	case JSON:
+		res.ForEach(func(key, value Result) bool {
+			switch key.String() {
+			case "key", "path":
+				a.key = value.String()
+			case "desc":
+				a.desc = value.Bool()
+			}
+			return true
+		})
+	default:
+		a.key = arg
+	}
+	return a
+}
+
+// aggItems returns the elements of an array, or the lines of a JSON Lines
+// document, along with the value of the key path of each one of them.
+// Elements without the key are left out, unless keepMissing is true, and
+// then their value does not exist.
+func aggItems(json, key string, keepMissing bool) (items, values []Result) {
+	add := func(value Result) bool {
+		item := value
+		if key != "" {
+			value = value.Get(key)
+			if !value.Exists() && !keepMissing {
+				return true
+			}
+		}
+		items = append(items, item)
+		values = append(values, value)
+		return true
+	}
+	if res := Parse(json); res.IsArray() {
+		res.ForEach(func(_, value Result) bool { return add(value) })
+	} else {
+		ForEachLine(json, add)
+	}
+	return items, values
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// @sum adds up the numbers of an array.
+//
+//	[1,2,3] -> 6
+//	[{"price":1.5},{"price":2}] @sum:"price" -> 3.5
+//
+// Values that are not numbers are skipped.
+func modSum(json, arg string) string {
+	_, values := aggItems(json, parseAggArgs(arg).key, false)
+	var sum float64
+	for _, value := range values {
+		if value.Type == Number {
+			sum += value.Num
+		}
+	}
+	return formatNumber(sum)
+}
+
+// @avg returns the average of the numbers of an array.
+//
+//	[1,2,3,6] -> 3
+//
+// Values that are not numbers are skipped. An empty string is returned when
+// there are no numbers.
+func modAvg(json, arg string) string {
+	_, values := aggItems(json, parseAggArgs(arg).key, false)
+	var sum float64
+	var n int
+	for _, value := range values {
+		if value.Type == Number {
+			sum += value.Num
+			n++
+		}
+	}
+	if n == 0 {
+		return ""
+	}
+	return formatNumber(sum / float64(n))
+}
+
+// @min returns the smallest value of an array, following Result.Less.
+//
+//	[3,1,2] -> 1
+//	["b","a"] -> "a"
+//
+// An empty string is returned for an empty array.
+func modMin(json, arg string) string {
+	return aggExtreme(json, arg, false)
+}
+
+// @max returns the largest value of an array, following Result.Less.
+//
+//	[3,1,2] -> 3
+func modMax(json, arg string) string {
+	return aggExtreme(json, arg, true)
+}
+
+func aggExtreme(json, arg string, max bool) string {
+	_, values := aggItems(json, parseAggArgs(arg).key, false)
+	if len(values) == 0 {
+		return ""
+	}
+	best := values[0]
+	for _, value := range values[1:] {
+		if max && best.Less(value, true) || !max && value.Less(best, true) {
+			best = value
+		}
+	}
+	return best.Raw
+}
+
+// @count returns the number of elements of an array, or the number of
+// lines of a JSON Lines document. With a path arg only the elements having
+// the path are counted.
+//
+//	[{"a":1},{"b":2},{"a":3}] @count:"a" -> 2
+func modCount(json, arg string) string {
+	items, _ := aggItems(json, parseAggArgs(arg).key, false)
+	return strconv.Itoa(len(items))
+}
+
+// @distinct removes the duplicate elements of an array, keeping the first
+// of each. With a path arg the elements are compared by the value of the
+// path, and the elements without it are all kept.
+//
+//	[1,2,1,"1"] -> [1,2,"1"]
+//	[{"a":1,"b":1},{"a":1,"b":2},{"b":3}] @distinct:"a" -> [{"a":1,"b":1},{"b":3}]
+func modDistinct(json, arg string) string {
+	items, values := aggItems(json, parseAggArgs(arg).key, true)
+	order := aggOrder(values, false)
+	dup := make([]bool, len(items))
+	for i := 1; i < len(order); i++ {
+		prev, cur := values[order[i-1]], values[order[i]]
+		if cur.Exists() && !prev.Less(cur, true) && !cur.Less(prev, true) {
+			dup[order[i]] = true
+		}
+	}
+	var out []byte
+	out = append(out, '[')
+	var n int
+	for i, item := range items {
+		if dup[i] {
+			continue
+		}
+		if n > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, item.Raw...)
+		n++
+	}
+	out = append(out, ']')
+	return bytesString(out)
+}
+
+// @sort sorts the elements of an array, following Result.Less. The sort is
+// stable. The arg is a path to sort by, or an object with a "key" path
+// and a "desc" flag for descending order. The elements without the path
+// are put last.
+//
+//	[3,1,2] -> [1,2,3]
+//	[{"age":44},{"n":1},{"age":31}] @sort:"age" -> [{"age":31},{"age":44},{"n":1}]
+//	[1,3,2] @sort:{"desc":true} -> [3,2,1]
+func modSort(json, arg string) string {
+	args := parseAggArgs(arg)
+	items, values := aggItems(json, args.key, true)
+	var out []byte
+	out = append(out, '[')
+	for i, j := range aggOrder(values, args.desc) {
+		if i > 0 {
+			out = append(out, ',')
+		}
+		out = append(out, items[j].Raw...)
+	}
+	out = append(out, ']')
+	return bytesString(out)
+}
+
+// aggOrder returns the positions of the values in a stable sorted order.
+// The values that do not exist are put last in both orders.
+func aggOrder(values []Result, desc bool) []int {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if a, b := values[order[i]].Exists(), values[order[j]].Exists(); !a || !b {
+			return a
+		}
+		if desc {
+			return values[order[j]].Less(values[order[i]], true)
+		}
+		return values[order[i]].Less(values[order[j]], true)
+	})
+	return order
+}
+
 // stringHeader instead of reflect.StringHeader
 type stringHeader struct {
 	data unsafe.Pointer
@@ -3196,10 +4068,18 @@ type sliceHeader struct {
 // results as uniquely allocated data. This operation is intended to minimize
 // copies and allocations for the large json string->[]byte.
 func getBytes(json []byte, path string, optionsFns ...PathOptionFn) Result {
+	return getBytesWith(json, func(json string) Result {
+		return Get(json, path, optionsFns...)
+	})
+}
+
+// getBytesWith is the same as getBytes but runs the get function for the
+// lookup, allowing compiled paths to share the safe copying of results.
+func getBytesWith(json []byte, get func(json string) Result) Result {
 	var result Result
 	if json != nil {
 		// unsafe cast to string
-		result = Get(*(*string)(unsafe.Pointer(&json)), path, optionsFns...)
+		result = get(*(*string)(unsafe.Pointer(&json)))
 		// safely get the string headers
 		rawhi := *(*stringHeader)(unsafe.Pointer(&result.Raw))
 		strhi := *(*stringHeader)(unsafe.Pointer(&result.Str))
@@ -3357,16 +4237,37 @@ func (t Result) Paths(json string) []string {
 // when the Result came from a path that contained a multipath, modifier,
 // or a nested query.
 func (t Result) Path(json string) string {
+	comps, ok := t.pathComps(json)
+	if !ok {
+		return ""
+	}
+	if len(comps) == 0 {
+		if DisableModifiers {
+			return ""
+		}
+		return "@this"
+	}
 	var path []byte
+	for _, comp := range comps {
+		path = append(path, '.')
+		path = append(path, escapeComp(comp)...)
+	}
+	return string(path[1:])
+}
+
+// pathComps returns the unescaped components of the path to the Result,
+// from the root down. See Path for when the components cannot be
+// determined.
+func (t Result) pathComps(json string) ([]string, bool) {
 	var comps []string // raw components
 	i := t.Index - 1
 	if t.Index+len(t.Raw) > len(json) {
 		// JSON cannot safely contain Result.
-		goto fail
+		return nil, false
 	}
 	if !strings.HasPrefix(json[t.Index:], t.Raw) {
 		// Result is not at the JSON index as exepcted.
-		goto fail
+		return nil, false
 	}
 	for ; i >= 0; i-- {
 		if json[i] <= ' ' {
@@ -3390,7 +4291,7 @@ func (t Result) Path(json string) string {
 		} else if json[i] == '{' {
 			// Encountered an open object. The original result was probably an
 			// object key.
-			goto fail
+			return nil, false
 		} else if json[i] == ',' || json[i] == '[' {
 			// inside an array, count the position
 			var arrIdx int
@@ -3402,7 +4303,7 @@ func (t Result) Path(json string) string {
 				if json[i] == ':' {
 					// Encountered an unexpected colon. The original result was
 					// probably an object key.
-					goto fail
+					return nil, false
 				} else if json[i] == ',' {
 					arrIdx++
 				} else if json[i] == '[' {
@@ -3415,27 +4316,15 @@ func (t Result) Path(json string) string {
 			}
 		}
 	}
-	if len(comps) == 0 {
-		if DisableModifiers {
-			goto fail
-		}
-		return "@this"
-	}
+	path := make([]string, 0, len(comps))
 	for i := len(comps) - 1; i >= 0; i-- {
 		rcomp := Parse(comps[i])
 		if !rcomp.Exists() {
-			goto fail
+			return nil, false
 		}
-		comp := escapeComp(rcomp.String())
-		path = append(path, '.')
-		path = append(path, comp...)
-	}
-	if len(path) > 0 {
-		path = path[1:]
+		path = append(path, rcomp.String())
 	}
-	return string(path)
-fail:
-	return ""
+	return path, true
 }
 
 // isSafePathKeyChar returns true if the input character is safe for not
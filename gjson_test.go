@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -892,6 +894,47 @@ func TestManyBasic(t *testing.T) {
 	testMany(true, `[world]`, strings.Repeat("a.", 70)+"hello")
 }
 
+func TestGetManyTrie(t *testing.T) {
+	paths := []string{
+		"name.last", "name.first", "name", "age", "children.1", "children.-1",
+		"children.#", "children.01", `fav\.movie`, "friends.1.nets.0", "friends.2.first",
+		"friends.#.first", "friends.#(last=Murphy)#.first", "friends.1|nets|@reverse",
+		"friends.0.nothing", "name.first.x", "children.x", "friends.1.nets.5", "@this.age",
+		"{name.first,age}", "friends.0", "friends.0.nets", "name.", "name|last", "",
+	}
+	for _, json := range []string{
+		readmeJSON, `  {"a":1,"a":2,"age":[3]}`, `[{"age":1}]`, `1`,
+		`{"x":null,"name":true,"age":false,"children":[null,true,false]}`,
+		`{"name":{"first":"A"},"name":{"last":null},"friends":{"1":1},"friends":[0,{"nets":[1,2]}]}`,
+	} {
+		res := GetMany(json, paths...)
+		bres := GetManyBytes([]byte(json), paths...)
+		for i, path := range paths {
+			expect := Get(json, path)
+			if !reflect.DeepEqual(res[i], expect) || !reflect.DeepEqual(bres[i], expect) {
+				t.Fatalf("path '%v': expected '%#v', got '%#v'", path, expect, res[i])
+			}
+		}
+	}
+	res := GetMany(`{"a":1,"a":2}`, "a", "a")
+	assert(t, res[0].Raw == "1" && res[1].Raw == "1")
+
+	// a duplicate parent key, with a few paths and with the trie
+	json := `{"name":{"first":"Tom"},"b":[1,2],"name":{"last":null}}`
+	for _, n := range []int{1, manyTrieMinPaths} {
+		paths := make([]string, n)
+		for i := range paths {
+			paths[i] = []string{"name.last", "b|1", "name.first"}[i%3]
+		}
+		res = GetMany(json, paths...)
+		for i, path := range paths {
+			expect := Get(json, path)
+			assert(t, reflect.DeepEqual(res[i], expect))
+		}
+		assert(t, res[0].Type == Null && res[0].Index == strings.LastIndex(json, "null"))
+	}
+}
+
 func testMany(t *testing.T, json string, paths, expected []string) {
 	testManyAny(t, json, paths, expected, true)
 	testManyAny(t, json, paths, expected, false)
@@ -1386,10 +1429,11 @@ func TestArrayValues(t *testing.T) {
 	}
 	expect := strings.Join([]string{
 		`jj.Result{Type:3, Raw:"\"PERSON1\"", Str:"PERSON1", Num:0, ` +
-			`Index:11, Indexes:[]int(nil)}`,
+			`Index:11, Indexes:[]int(nil), useNumber:false}`,
 		`jj.Result{Type:3, Raw:"\"PERSON2\"", Str:"PERSON2", Num:0, ` +
-			`Index:21, Indexes:[]int(nil)}`,
-		`jj.Result{Type:2, Raw:"0", Str:"", Num:0, Index:31, Indexes:[]int(nil)}`,
+			`Index:21, Indexes:[]int(nil), useNumber:false}`,
+		`jj.Result{Type:2, Raw:"0", Str:"", Num:0, Index:31, Indexes:[]int(nil), ` +
+			`useNumber:false}`,
 	}, "\n")
 	if output != expect {
 		t.Fatalf("expected '%v', got '%v'", expect, output)
@@ -2627,3 +2671,199 @@ func TestIssue301(t *testing.T) {
 	assert(t, Get(json, `fav\.movie.1`).String() == "")
 	assert(t, Get(json, `fav\.movie.[1]`).String() == "[]")
 }
+
+func TestRecursiveDescent(t *testing.T) {
+	json := `{
+		"id": 1,
+		"a": {"id": 2, "items": [{"id": 3, "price": 5}, {"id": 4, "price": 20}]},
+		"b": [{"id": "x"}],
+		"s": "{\"id\":9}"
+	}`
+	assert(t, Get(json, `**.id`).Raw == `[1,2,3,4,"x"]`)
+	assert(t, Get(json, `a.**.id`).Raw == `[2,3,4]`)
+	assert(t, Get(json, `**.id|#`).Int() == 5)
+	assert(t, Get(json, `**.id|@reverse|0`).String() == "x")
+	assert(t, Get(json, `**.items.#(price>10).id`).Raw == `[4]`)
+	assert(t, Get(json, `**.#(price<10)#.id|@flatten`).Raw == `[3]`)
+	assert(t, Get(json, `b.**`).Raw == `[{"id": "x"},"x"]`)
+	assert(t, Get(json, `**.nothing`).Raw == `[]`)
+	assert(t, Get(json, `\*\*`).Raw == ``)
+	res := Get(json, `**.id`)
+	var i int
+	res.ForEach(func(_, value Result) bool {
+		assert(t, json[res.Indexes[i]:res.Indexes[i]+len(value.Raw)] == value.Raw)
+		i++
+		return true
+	})
+	assert(t, i == 5)
+
+	lines := `{"id":1,"c":{"id":2}}` + "\n" + `{"id":3}` + "\n"
+	assert(t, Get(lines, `..**.id`).Raw == `[1,2,3]`)
+	assert(t, Get(lines, `..1.**.id`).Raw == `[3]`)
+	assert(t, Get(lines, `..#.id`).Raw == `[1,3]`)
+	assert(t, Get(lines, `..#`).Int() == 2)
+}
+
+func TestArraySlice(t *testing.T) {
+	json := `{"children":["Sara","Alex","Jack"],"friends":[{"first":"Dale","age":44},{"first":"Roger","age":68},{"first":"Jane","age":47}]}`
+	tests := []struct {
+		path   string
+		expect string
+	}{
+		{`children.[1:3]`, `["Alex","Jack"]`},
+		{`children.[-2:]`, `["Alex","Jack"]`},
+		{`children.[::2]`, `["Sara","Jack"]`},
+		{`children.[::-1]`, `["Jack","Alex","Sara"]`},
+		{`children.[ : 1 ]`, `["Sara"]`},
+		{`children.[5:]`, `[]`},
+		{`children.[::0]`, `[]`},
+		{`children.[1:3]|0`, `"Alex"`},
+		{`children.[:2]|#`, `2`},
+		{`children.[:2].@reverse`, `["Alex","Sara"]`},
+		{`friends.[1:].first`, `["Roger","Jane"]`},
+		{`friends.[1:].age|@reverse`, `[47,68]`},
+		{`children.[0]`, `["Sara"]`},
+		{`children.[1:2:3:4]`, `[]`},
+		{`children.-1`, `"Jack"`},
+		{`friends.-3.first`, `"Dale"`},
+		{`children.-4`, ``},
+	}
+	for _, tt := range tests {
+		if got := Get(json, tt.path).Raw; got != tt.expect {
+			t.Fatalf("path '%v': expected '%v', got '%v'", tt.path, tt.expect, got)
+		}
+	}
+	assert(t, Get(json, `children.-1`, DisableNegativeIndex(true)).Raw == ``)
+	assert(t, Get(`[1,2,3]`, `[1:]`).Raw == `[2,3]`)
+	res := Get(json, `friends.[::-1].first`)
+	assert(t, len(res.Indexes) == 3)
+	for i, value := range res.Array() {
+		assert(t, json[res.Indexes[i]:res.Indexes[i]+len(value.Raw)] == value.Raw)
+	}
+
+	lines := `{"a":1}` + "\n" + `{"a":2}` + "\n" + `{"a":3}` + "\n"
+	assert(t, Get(lines, `..[0:2].a`).Raw == `[1,2]`)
+	assert(t, Get(lines, `..[-1:]`).Raw == `[{"a":3}]`)
+	assert(t, Get(lines, `..-1.a`).Raw == `3`)
+}
+
+func TestAggregateModifiers(t *testing.T) {
+	json := `{
+		"items": [
+			{"name": "b", "price": 1.5},
+			{"name": "A", "price": 2},
+			{"name": "c"},
+			{"name": "b", "price": "3"}
+		],
+		"nums": [3, 1, 2, 1, "1"]
+	}`
+	tests := []struct {
+		path   string
+		expect string
+	}{
+		{`nums|@sum`, `7`},
+		{`items|@sum:"price"`, `3.5`},
+		{`items.@sum:price`, `3.5`},
+		{`items|@sum:{"key":"price"}`, `3.5`},
+		{`items|@avg:price`, `1.75`},
+		{`nums|@avg`, `1.75`},
+		{`nothing|@avg`, ``},
+		{`items|@min:price`, `1.5`},
+		{`items|@max:price`, `"3"`},
+		{`nums|@max`, `"1"`},
+		{`items.#.name|@min`, `"A"`},
+		{`[]|@min`, ``},
+		{`items|@count`, `4`},
+		{`items|@count:price`, `3`},
+		{`nums|@distinct`, `[3,1,2,"1"]`},
+		{`items|@distinct:name|#.name`, `["b","A","c"]`},
+		{`nums|@sort`, `[1,1,2,3,"1"]`},
+		{`nums|@sort:{"desc":true}`, `["1",3,2,1,1]`},
+		{`items|@sort:name|#.name`, `["A","b","b","c"]`},
+		{`items|@sort:{"key":"price","desc":true}|#.price`, `["3",2,1.5]`},
+		// the elements without the key are kept, last
+		{`items|@sort:price|#.name`, `["b","A","b","c"]`},
+		{`items|@sort:{"key":"price","desc":true}|#.name`, `["b","A","b","c"]`},
+		{`items|@distinct:price|#.name`, `["b","A","c","b"]`},
+	}
+	for _, tt := range tests {
+		if got := Get(json, tt.path).Raw; got != tt.expect {
+			t.Fatalf("path '%v': expected '%v', got '%v'", tt.path, tt.expect, got)
+		}
+	}
+
+	keyless := `[{"a":2},{"b":1},{"a":1},{"b":2},{"a":2}]`
+	assert(t, Get(keyless, `@sort:"a"`).Raw == `[{"a":1},{"a":2},{"a":2},{"b":1},{"b":2}]`)
+	assert(t, Get(keyless, `@distinct:"a"`).Raw == `[{"a":2},{"b":1},{"a":1},{"b":2}]`)
+
+	lines := `{"a":1}` + "\n" + `{"a":5}` + "\n" + `{"b":2}` + "\n"
+	assert(t, Get(lines, `@sum:a`).Raw == `6`)
+	assert(t, Get(lines, `@count`).Raw == `3`)
+	assert(t, Get(lines, `@count:a`).Raw == `2`)
+	assert(t, Get(lines, `@sort:{"key":"a","desc":true}|0.a`).Raw == `5`)
+	assert(t, Get(lines, `@sort:{"key":"a","desc":true}|2.b`).Raw == `2`)
+}
+
+func TestExtendedQueries(t *testing.T) {
+	json := `{
+		"friends": [
+			{"first": "Dale", "last": "Murphy", "age": 44, "city": "Paris", "nets": ["ig", "fb", "tw"], "pet": {"kind": "dog"}},
+			{"first": "Roger", "last": "Craig", "age": 68, "city": "Rome", "nets": ["fb", "tw"]},
+			{"first": "Jane", "last": "Murphy", "age": 47, "city": "Paris", "nets": ["ig", "tw"], "id": "1", "active": true}
+		],
+		"nums": [1, 2, 3, "2"],
+		"strs": ["apple", "banana", "cherry"]
+	}`
+	tests := []struct {
+		path   string
+		expect string
+	}{
+		{`friends.#(age>45 && city=="Paris").first`, `"Jane"`},
+		{`friends.#(age>45 && city=="Paris")#.first`, `["Jane"]`},
+		{`friends.#(age<45 || age>60)#.first`, `["Dale","Roger"]`},
+		{`friends.#(age > 45 && city == Paris)#.first`, `["Jane"]`},
+		{`friends.#(!(age>45))#.first`, `["Dale"]`},
+		{`friends.#((age>45 || first=="Dale") && city=="Paris")#.first`, `["Dale","Jane"]`},
+		{`friends.#(age>45 && city=="Paris" || first=="Dale")#.first`, `["Dale","Jane"]`},
+		{`friends.#(first=~"^[DJ]")#.first`, `["Dale","Jane"]`},
+		{`friends.#(first!~"^[DJ]")#.first`, `["Roger"]`},
+		{`friends.#(first=~"^r")#.first`, `[]`},
+		{`friends.#(first=~"(?i)^r")#.first`, `["Roger"]`},
+		{`friends.#(city in ["Rome","Oslo"])#.first`, `["Roger"]`},
+		{`friends.#(id in [1])#.first`, `[]`},
+		{`friends.#(id in ["1"])#.first`, `["Jane"]`},
+		{`friends.#(nets ∋ "fb")#.first`, `["Dale","Roger"]`},
+		{`friends.#(nets contains "ig")#.first`, `["Dale","Jane"]`},
+		{`friends.#(first contains "an")#.first`, `["Jane"]`},
+		{`friends.#(exists(pet))#.first`, `["Dale"]`},
+		{`friends.#(exists(pet.kind) && pet.kind=="dog").first`, `"Dale"`},
+		{`friends.#(!exists(pet) && last==Murphy)#.first`, `["Jane"]`},
+		{`friends.#(nets.#(=="fb") && age>50)#.first`, `["Roger"]`},
+		{`friends.#(active==~true || age>60)#.first`, `["Roger","Jane"]`},
+		{`nums.#(in [2,3])#`, `[2,3]`},
+		{`nums.#(>1 && <3)#`, `[2,"2"]`},
+		{`strs.#(=~"an")#`, `["banana"]`},
+		{`strs.#(!="apple" && !="cherry")`, `"banana"`},
+		// the classic syntax is unchanged
+		{`friends.#(last=="Murphy")#.first`, `["Dale","Jane"]`},
+		{`friends.#(first=="a && b")#.first`, `[]`},
+		{`friends.#(active=~true)#.first`, `["Jane"]`},
+		{`friends.#(first%"D*").last`, `"Murphy"`},
+		// a malformed extended query matches nothing
+		{`friends.#(age>40 &&)#.first`, `[]`},
+		{`friends.#(name=~"[")#.first`, `[]`},
+	}
+	for _, tt := range tests {
+		if got := Get(json, tt.path).Raw; got != tt.expect {
+			t.Fatalf("path '%v': expected '%v', got '%v'", tt.path, tt.expect, got)
+		}
+		if got := CompilePath(tt.path).Get(json).Raw; got != tt.expect {
+			t.Fatalf("compiled path '%v': expected '%v', got '%v'", tt.path, tt.expect, got)
+		}
+	}
+	_, err := GetE(json, `friends.#(age>40 &&)#.first`)
+	var e *GetError
+	assert(t, errors.As(err, &e) && e.Err == ErrInvalidPath && e.Column == 11)
+	_, err = GetE(json, `friends.#(age>40 && city=="Paris")#.first`)
+	assert(t, err == nil)
+}
@@ -0,0 +1,1029 @@
+package jj
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// JSONPath is a compiled RFC 9535 JSONPath query.
+// A JSONPath is safe for concurrent use by multiple goroutines.
+type JSONPath struct {
+	expr  string
+	query *jpQuery
+}
+
+// JSONPathError describes a syntax error in a JSONPath expression.
+type JSONPathError struct {
+	// Expr is the JSONPath expression.
+	Expr string
+	// Offset is the byte offset in Expr where the error was found.
+	Offset int
+	// Msg describes the error.
+	Msg string
+}
+
+func (e *JSONPathError) Error() string {
+	return fmt.Sprintf("jsonpath: %s at offset %d of %q", e.Msg, e.Offset, e.Expr)
+}
+
+// CompileJSONPath parses an RFC 9535 JSONPath expression, like
+// "$.store.book[?@.price < 10].title", for later use.
+func CompileJSONPath(expr string) (*JSONPath, error) {
+	p := &jpParser{expr: expr}
+	p.ws()
+	if !p.eat('$') {
+		return nil, p.errorf("expected '$'")
+	}
+	q, err := p.parseSegments()
+	if err != nil {
+		return nil, err
+	}
+	p.ws()
+	if p.i < len(p.expr) {
+		return nil, p.errorf("unexpected character %q", p.expr[p.i])
+	}
+	return &JSONPath{expr: expr, query: q}, nil
+}
+
+// String returns the JSONPath expression that was compiled.
+func (jp *JSONPath) String() string {
+	return jp.expr
+}
+
+// Query returns the nodes of json that are selected by the JSONPath.
+// The Index of each Result is the position of its raw value in json, so that
+// Result.Path can be used to get the GJSON path of the node.
+func (jp *JSONPath) Query(json string) []Result {
+	_, root, ok := parseAny(json, 0, true)
+	if !ok {
+		return nil
+	}
+	return jp.query.nodes(&jpContext{root: root}, root)
+}
+
+// QueryJSONPath returns the nodes of json that are selected by the RFC 9535
+// JSONPath expression, like:
+//
+//	jj.QueryJSONPath(json, "$.store.book[?@.price < 10].title")
+//	jj.QueryJSONPath(json, "$..author")
+//	jj.QueryJSONPath(json, "$.store.book[-1:]")
+//
+// Nil is returned when the expression is not valid, use CompileJSONPath to
+// get the syntax error.
+func QueryJSONPath(json, expr string) []Result {
+	jp, err := CompileJSONPath(expr)
+	if err != nil {
+		return nil
+	}
+	return jp.Query(json)
+}
+
+type jpContext struct {
+	root Result
+}
+
+type jpQuery struct {
+	segs []jpSegment
+}
+
+type jpSegment struct {
+	descendant bool
+	sels       []jpSelector
+}
+
+type jpSelectorKind int
+
+const (
+	jpName jpSelectorKind = iota
+	jpWildcard
+	jpIndex
+	jpSlice
+	jpFilter
+)
+
+type jpSelector struct {
+	kind   jpSelectorKind
+	name   string
+	index  int
+	slice  [3]int
+	sliceN [3]bool // which slice parts are present
+	filter jpLogical
+}
+
+// singular reports whether the query selects at most one node.
+func (q *jpQuery) singular() bool {
+	for _, seg := range q.segs {
+		if seg.descendant || len(seg.sels) != 1 {
+			return false
+		}
+		if k := seg.sels[0].kind; k != jpName && k != jpIndex {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *jpQuery) nodes(ctx *jpContext, start Result) []Result {
+	nodes := []Result{start}
+	for _, seg := range q.segs {
+		var next []Result
+		for _, node := range nodes {
+			if seg.descendant {
+				descend(node, func(n Result) {
+					next = seg.apply(ctx, n, next)
+				})
+			} else {
+				next = seg.apply(ctx, node, next)
+			}
+		}
+		nodes = next
+		if len(nodes) == 0 {
+			break
+		}
+	}
+	return nodes
+}
+
+func jpChildren(node Result) []Result {
+	var children []Result
+	if node.IsJSON() {
+		node.ForEach(func(_, value Result) bool {
+			children = append(children, value)
+			return true
+		})
+	}
+	return children
+}
+
+func (seg *jpSegment) apply(ctx *jpContext, node Result, out []Result) []Result {
+	for i := range seg.sels {
+		out = seg.sels[i].apply(ctx, node, out)
+	}
+	return out
+}
+
+func (sel *jpSelector) apply(ctx *jpContext, node Result, out []Result) []Result {
+	switch sel.kind {
+	case jpName:
+		if node.IsObject() {
+			node.ForEach(func(key, value Result) bool {
+				if key.Str == sel.name {
+					out = append(out, value)
+					return false
+				}
+				return true
+			})
+		}
+	case jpWildcard:
+		out = append(out, jpChildren(node)...)
+	case jpIndex:
+		if node.IsArray() {
+			elems := jpChildren(node)
+			i := sel.index
+			if i < 0 {
+				i += len(elems)
+			}
+			if i >= 0 && i < len(elems) {
+				out = append(out, elems[i])
+			}
+		}
+	case jpSlice:
+		if node.IsArray() {
+			elems := jpChildren(node)
+			start, end, step := sliceBounds(len(elems), sel.slice, sel.sliceN)
+			if step > 0 {
+				for i := start; i < end; i += step {
+					out = append(out, elems[i])
+				}
+			} else if step < 0 {
+				for i := start; i > end; i += step {
+					out = append(out, elems[i])
+				}
+			}
+		}
+	case jpFilter:
+		for _, child := range jpChildren(node) {
+			if sel.filter.test(ctx, child) {
+				out = append(out, child)
+			}
+		}
+	}
+	return out
+}
+
+// jpLogical is a filter expression yielding a logical value.
+type jpLogical interface {
+	test(ctx *jpContext, cur Result) bool
+}
+
+// jpComparable is a filter expression yielding a value, or nothing.
+type jpComparable interface {
+	value(ctx *jpContext, cur Result) (Result, bool)
+}
+
+type jpOr []jpLogical
+
+func (x jpOr) test(ctx *jpContext, cur Result) bool {
+	for _, e := range x {
+		if e.test(ctx, cur) {
+			return true
+		}
+	}
+	return false
+}
+
+type jpAnd []jpLogical
+
+func (x jpAnd) test(ctx *jpContext, cur Result) bool {
+	for _, e := range x {
+		if !e.test(ctx, cur) {
+			return false
+		}
+	}
+	return true
+}
+
+type jpNot struct{ x jpLogical }
+
+func (x jpNot) test(ctx *jpContext, cur Result) bool {
+	return !x.x.test(ctx, cur)
+}
+
+// jpQueryExpr is an embedded @ or $ query.
+type jpQueryExpr struct {
+	relative bool
+	query    *jpQuery
+}
+
+func (x *jpQueryExpr) nodes(ctx *jpContext, cur Result) []Result {
+	if x.relative {
+		return x.query.nodes(ctx, cur)
+	}
+	return x.query.nodes(ctx, ctx.root)
+}
+
+// test is the existence test of a filter query.
+func (x *jpQueryExpr) test(ctx *jpContext, cur Result) bool {
+	return len(x.nodes(ctx, cur)) > 0
+}
+
+// value is the value of a singular query.
+func (x *jpQueryExpr) value(ctx *jpContext, cur Result) (Result, bool) {
+	nodes := x.nodes(ctx, cur)
+	if len(nodes) != 1 {
+		return Result{}, false
+	}
+	return nodes[0], true
+}
+
+type jpLiteral struct{ v Result }
+
+func (x jpLiteral) value(*jpContext, Result) (Result, bool) {
+	return x.v, true
+}
+
+type jpCompare struct {
+	op   string
+	l, r jpComparable
+}
+
+func (x *jpCompare) test(ctx *jpContext, cur Result) bool {
+	l, lok := x.l.value(ctx, cur)
+	r, rok := x.r.value(ctx, cur)
+	switch x.op {
+	case "==":
+		return jpEqual(l, lok, r, rok)
+	case "!=":
+		return !jpEqual(l, lok, r, rok)
+	case "<":
+		return jpLess(l, lok, r, rok)
+	case "<=":
+		return jpLess(l, lok, r, rok) || jpEqual(l, lok, r, rok)
+	case ">":
+		return jpLess(r, rok, l, lok)
+	case ">=":
+		return jpLess(r, rok, l, lok) || jpEqual(l, lok, r, rok)
+	}
+	return false
+}
+
+func jpEqual(a Result, aok bool, b Result, bok bool) bool {
+	if !aok || !bok {
+		return !aok && !bok
+	}
+	return jsonEqual(a, b)
+}
+
+// jsonEqual reports whether two json values are equal, where numbers are
+// compared numerically and object members are compared regardless of order.
+func jsonEqual(a, b Result) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case Number:
+		return a.Num == b.Num
+	case String:
+		return a.Str == b.Str
+	case JSON:
+		if a.IsArray() != b.IsArray() {
+			return false
+		}
+		if a.IsArray() {
+			ea, eb := jpChildren(a), jpChildren(b)
+			if len(ea) != len(eb) {
+				return false
+			}
+			for i := range ea {
+				if !jsonEqual(ea[i], eb[i]) {
+					return false
+				}
+			}
+			return true
+		}
+		ma, mb := a.Map(), b.Map()
+		if len(ma) != len(mb) {
+			return false
+		}
+		for k, va := range ma {
+			vb, ok := mb[k]
+			if !ok || !jsonEqual(va, vb) {
+				return false
+			}
+		}
+		return true
+	}
+	return true
+}
+
+func jpLess(a Result, aok bool, b Result, bok bool) bool {
+	if !aok || !bok || a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case Number:
+		return a.Num < b.Num
+	case String:
+		return a.Str < b.Str
+	}
+	return false
+}
+
+// jpFunc is a function extension: length, count, value, match or search.
+type jpFunc struct {
+	name string
+	args []any // jpComparable or *jpQueryExpr
+	re   *regexp.Regexp
+}
+
+func (x *jpFunc) logical() bool {
+	return x.name == "match" || x.name == "search"
+}
+
+func (x *jpFunc) argValue(ctx *jpContext, cur Result, i int) (Result, bool) {
+	switch a := x.args[i].(type) {
+	case *jpQueryExpr:
+		return a.value(ctx, cur)
+	case jpComparable:
+		return a.value(ctx, cur)
+	}
+	return Result{}, false
+}
+
+func (x *jpFunc) value(ctx *jpContext, cur Result) (Result, bool) {
+	switch x.name {
+	case "length":
+		v, ok := x.argValue(ctx, cur, 0)
+		if !ok {
+			return Result{}, false
+		}
+		var n int
+		switch {
+		case v.Type == String:
+			n = utf8.RuneCountInString(v.Str)
+		case v.IsJSON():
+			v.ForEach(func(_, _ Result) bool {
+				n++
+				return true
+			})
+		default:
+			return Result{}, false
+		}
+		return jpNumber(n), true
+	case "count":
+		return jpNumber(len(x.args[0].(*jpQueryExpr).nodes(ctx, cur))), true
+	case "value":
+		return x.args[0].(*jpQueryExpr).value(ctx, cur)
+	}
+	return Result{}, false
+}
+
+func (x *jpFunc) test(ctx *jpContext, cur Result) bool {
+	s, ok := x.argValue(ctx, cur, 0)
+	if !ok || s.Type != String {
+		return false
+	}
+	re := x.re
+	if re == nil {
+		pattern, ok := x.argValue(ctx, cur, 1)
+		if !ok || pattern.Type != String {
+			return false
+		}
+		var err error
+		if re, err = compileIRegexp(pattern.Str, x.name == "match"); err != nil {
+			return false
+		}
+	}
+	return re.MatchString(s.Str)
+}
+
+// compileIRegexp compiles an RFC 9485 I-Regexp, anchored for full matches.
+func compileIRegexp(pattern string, full bool) (*regexp.Regexp, error) {
+	// In I-Regexp the dot does not match line breaks.
+	var b []byte
+	var class bool
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			b = append(b, c, pattern[i+1])
+			i++
+		case c == '[':
+			class = true
+			b = append(b, c)
+		case c == ']':
+			class = false
+			b = append(b, c)
+		case c == '.' && !class:
+			b = append(b, `[^\n\r]`...)
+		default:
+			b = append(b, c)
+		}
+	}
+	if full {
+		return regexp.Compile(`^(?:` + string(b) + `)$`)
+	}
+	return regexp.Compile(string(b))
+}
+
+func jpNumber(n int) Result {
+	return Result{Type: Number, Num: float64(n), Raw: strconv.Itoa(n)}
+}
+
+type jpParser struct {
+	expr string
+	i    int
+}
+
+func (p *jpParser) errorf(format string, args ...any) *JSONPathError {
+	return &JSONPathError{Expr: p.expr, Offset: p.i, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *jpParser) ws() {
+	for p.i < len(p.expr) {
+		switch p.expr[p.i] {
+		case ' ', '\t', '\n', '\r':
+			p.i++
+		default:
+			return
+		}
+	}
+}
+
+func (p *jpParser) peek() byte {
+	if p.i < len(p.expr) {
+		return p.expr[p.i]
+	}
+	return 0
+}
+
+func (p *jpParser) eat(c byte) bool {
+	if p.peek() == c {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *jpParser) eatStr(s string) bool {
+	if len(p.expr)-p.i >= len(s) && p.expr[p.i:p.i+len(s)] == s {
+		p.i += len(s)
+		return true
+	}
+	return false
+}
+
+// parseSegments parses the segments of a query following its '$' or '@'.
+func (p *jpParser) parseSegments() (*jpQuery, error) {
+	q := &jpQuery{}
+	for {
+		save := p.i
+		p.ws()
+		var seg jpSegment
+		switch {
+		case p.eatStr(".."):
+			seg.descendant = true
+			switch {
+			case p.eat('*'):
+				seg.sels = []jpSelector{{kind: jpWildcard}}
+			case p.peek() == '[':
+				sels, err := p.parseBracket()
+				if err != nil {
+					return nil, err
+				}
+				seg.sels = sels
+			default:
+				name, ok := p.parseMemberName()
+				if !ok {
+					return nil, p.errorf("expected member name after '..'")
+				}
+				seg.sels = []jpSelector{{kind: jpName, name: name}}
+			}
+		case p.eat('.'):
+			if p.eat('*') {
+				seg.sels = []jpSelector{{kind: jpWildcard}}
+			} else {
+				name, ok := p.parseMemberName()
+				if !ok {
+					return nil, p.errorf("expected member name after '.'")
+				}
+				seg.sels = []jpSelector{{kind: jpName, name: name}}
+			}
+		case p.peek() == '[':
+			sels, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			seg.sels = sels
+		default:
+			// whitespace is not part of the query
+			p.i = save
+			return q, nil
+		}
+		q.segs = append(q.segs, seg)
+	}
+}
+
+func isJPNameFirst(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+}
+
+func (p *jpParser) parseMemberName() (string, bool) {
+	s := p.i
+	if s >= len(p.expr) || !isJPNameFirst(p.expr[s]) {
+		return "", false
+	}
+	p.i++
+	for p.i < len(p.expr) && (isJPNameFirst(p.expr[p.i]) || p.expr[p.i] >= '0' && p.expr[p.i] <= '9') {
+		p.i++
+	}
+	return p.expr[s:p.i], true
+}
+
+// parseBracket parses a bracketed selection like "['a', 0, 1:3, ?@.b]".
+func (p *jpParser) parseBracket() ([]jpSelector, error) {
+	p.eat('[')
+	var sels []jpSelector
+	for {
+		p.ws()
+		sel, err := p.parseSelector()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+		p.ws()
+		if p.eat(']') {
+			return sels, nil
+		}
+		if !p.eat(',') {
+			return nil, p.errorf("expected ',' or ']'")
+		}
+	}
+}
+
+func (p *jpParser) parseSelector() (jpSelector, error) {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		name, err := p.parseString()
+		return jpSelector{kind: jpName, name: name}, err
+	case c == '*':
+		p.i++
+		return jpSelector{kind: jpWildcard}, nil
+	case c == '?':
+		p.i++
+		p.ws()
+		f, err := p.parseOr()
+		return jpSelector{kind: jpFilter, filter: f}, err
+	case c == ':' || c == '-' || c >= '0' && c <= '9':
+		sel := jpSelector{kind: jpIndex}
+		for part := 0; part < 3; part++ {
+			p.ws()
+			if c := p.peek(); c == '-' || c >= '0' && c <= '9' {
+				n, err := p.parseInt()
+				if err != nil {
+					return sel, err
+				}
+				sel.slice[part], sel.sliceN[part] = n, true
+			}
+			p.ws()
+			if part == 2 || !p.eat(':') {
+				break
+			}
+			sel.kind = jpSlice
+		}
+		if sel.kind == jpIndex {
+			if !sel.sliceN[0] {
+				return sel, p.errorf("expected index")
+			}
+			sel.index = sel.slice[0]
+		}
+		return sel, nil
+	}
+	return jpSelector{}, p.errorf("unexpected character %q", p.peek())
+}
+
+func (p *jpParser) parseInt() (int, error) {
+	s := p.i
+	p.eat('-')
+	d := p.i
+	for p.i < len(p.expr) && p.expr[p.i] >= '0' && p.expr[p.i] <= '9' {
+		p.i++
+	}
+	digits := p.expr[d:p.i]
+	if len(digits) == 0 || (len(digits) > 1 && digits[0] == '0') ||
+		(digits == "0" && d > s) {
+		p.i = s
+		return 0, p.errorf("invalid integer")
+	}
+	n, err := strconv.ParseInt(p.expr[s:p.i], 10, 64)
+	if err != nil || n > 1<<53-1 || n < -(1<<53-1) {
+		p.i = s
+		return 0, p.errorf("integer out of range")
+	}
+	return int(n), nil
+}
+
+// parseString parses a single or double quoted string literal.
+func (p *jpParser) parseString() (string, error) {
+	quote := p.expr[p.i]
+	p.i++
+	var b []byte
+	for p.i < len(p.expr) {
+		c := p.expr[p.i]
+		switch {
+		case c == quote:
+			p.i++
+			return string(b), nil
+		case c < ' ':
+			return "", p.errorf("invalid character in string")
+		case c == '\\':
+			p.i++
+			if p.i >= len(p.expr) {
+				return "", p.errorf("unterminated string")
+			}
+			switch e := p.expr[p.i]; e {
+			case 'b':
+				b = append(b, '\b')
+			case 'f':
+				b = append(b, '\f')
+			case 'n':
+				b = append(b, '\n')
+			case 'r':
+				b = append(b, '\r')
+			case 't':
+				b = append(b, '\t')
+			case '/', '\\':
+				b = append(b, e)
+			case 'u':
+				r, ok := p.parseHexRune()
+				if !ok {
+					return "", p.errorf("invalid unicode escape")
+				}
+				b = utf8.AppendRune(b, r)
+				continue
+			default:
+				if e != quote {
+					return "", p.errorf("invalid escape")
+				}
+				b = append(b, e)
+			}
+			p.i++
+		default:
+			b = append(b, c)
+			p.i++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// parseHexRune parses the XXXX of a \uXXXX escape, including a following
+// low surrogate. p.i is at the 'u' and is left after the escape.
+func (p *jpParser) parseHexRune() (rune, bool) {
+	hex := func() (rune, bool) {
+		if p.i+5 > len(p.expr) {
+			return 0, false
+		}
+		n, err := strconv.ParseUint(p.expr[p.i+1:p.i+5], 16, 32)
+		if err != nil {
+			return 0, false
+		}
+		p.i += 5
+		return rune(n), true
+	}
+	r, ok := hex()
+	if !ok {
+		return 0, false
+	}
+	if utf16.IsSurrogate(r) {
+		if !p.eatStr(`\`) || p.peek() != 'u' {
+			return 0, false
+		}
+		r2, ok := hex()
+		if !ok {
+			return 0, false
+		}
+		r = utf16.DecodeRune(r, r2)
+		if r == utf8.RuneError {
+			return 0, false
+		}
+	}
+	return r, true
+}
+
+func (p *jpParser) parseOr() (jpLogical, error) {
+	var or jpOr
+	for {
+		and, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, and)
+		p.ws()
+		if !p.eatStr("||") {
+			break
+		}
+		p.ws()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *jpParser) parseAnd() (jpLogical, error) {
+	var and jpAnd
+	for {
+		x, err := p.parseBasic()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, x)
+		p.ws()
+		if !p.eatStr("&&") {
+			break
+		}
+		p.ws()
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+var jpCompareOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func (p *jpParser) parseBasic() (jpLogical, error) {
+	p.ws()
+	not := p.eat('!')
+	p.ws()
+	if p.eat('(') {
+		p.ws()
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.ws()
+		if !p.eat(')') {
+			return nil, p.errorf("expected ')'")
+		}
+		if not {
+			return jpNot{x}, nil
+		}
+		return x, nil
+	}
+	start := p.i
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	p.ws()
+	for _, op := range jpCompareOps {
+		if !p.eatStr(op) {
+			continue
+		}
+		if not {
+			p.i = start
+			return nil, p.errorf("'!' cannot negate a comparison")
+		}
+		l, err := p.comparable(left, start)
+		if err != nil {
+			return nil, err
+		}
+		p.ws()
+		rstart := p.i
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		r, err := p.comparable(right, rstart)
+		if err != nil {
+			return nil, err
+		}
+		return &jpCompare{op: op, l: l, r: r}, nil
+	}
+	var x jpLogical
+	switch v := left.(type) {
+	case *jpQueryExpr:
+		x = v
+	case *jpFunc:
+		if !v.logical() {
+			p.i = start
+			return nil, p.errorf("function %q result must be compared", v.name)
+		}
+		x = v
+	default:
+		p.i = start
+		return nil, p.errorf("literal must be compared")
+	}
+	if not {
+		return jpNot{x}, nil
+	}
+	return x, nil
+}
+
+// comparable checks that the operand can be used in a comparison.
+func (p *jpParser) comparable(x any, at int) (jpComparable, error) {
+	switch v := x.(type) {
+	case *jpQueryExpr:
+		if !v.query.singular() {
+			p.i = at
+			return nil, p.errorf("non-singular query is not comparable")
+		}
+		return v, nil
+	case *jpFunc:
+		if v.logical() {
+			p.i = at
+			return nil, p.errorf("function %q result is not comparable", v.name)
+		}
+		return v, nil
+	case jpComparable:
+		return v, nil
+	}
+	p.i = at
+	return nil, p.errorf("not comparable")
+}
+
+// parseOperand parses a literal, a query or a function expression.
+func (p *jpParser) parseOperand() (any, error) {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.i++
+		q, err := p.parseSegments()
+		if err != nil {
+			return nil, err
+		}
+		return &jpQueryExpr{relative: c == '@', query: q}, nil
+	case c == '\'' || c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return jpLiteral{Parse(string(AppendJSONString(nil, s)))}, nil
+	case c == '-' || c >= '0' && c <= '9':
+		return p.parseNumber()
+	case c >= 'a' && c <= 'z':
+		s := p.i
+		for p.i < len(p.expr) {
+			c := p.expr[p.i]
+			if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' {
+				p.i++
+				continue
+			}
+			break
+		}
+		name := p.expr[s:p.i]
+		if p.peek() == '(' {
+			return p.parseFunc(name, s)
+		}
+		switch name {
+		case "true":
+			return jpLiteral{Result{Type: True, Raw: name}}, nil
+		case "false":
+			return jpLiteral{Result{Type: False, Raw: name}}, nil
+		case "null":
+			return jpLiteral{Result{Type: Null, Raw: name}}, nil
+		}
+		p.i = s
+		return nil, p.errorf("unexpected %q", name)
+	}
+	return nil, p.errorf("unexpected character %q", p.peek())
+}
+
+func (p *jpParser) parseNumber() (any, error) {
+	s := p.i
+	p.eat('-')
+	d := p.i
+	for p.i < len(p.expr) && p.expr[p.i] >= '0' && p.expr[p.i] <= '9' {
+		p.i++
+	}
+	if p.i == d || (p.i-d > 1 && p.expr[d] == '0') {
+		p.i = s
+		return nil, p.errorf("invalid number")
+	}
+	if p.eat('.') {
+		f := p.i
+		for p.i < len(p.expr) && p.expr[p.i] >= '0' && p.expr[p.i] <= '9' {
+			p.i++
+		}
+		if p.i == f {
+			return nil, p.errorf("invalid number")
+		}
+	}
+	if p.eat('e') || p.eat('E') {
+		if !p.eat('+') {
+			p.eat('-')
+		}
+		e := p.i
+		for p.i < len(p.expr) && p.expr[p.i] >= '0' && p.expr[p.i] <= '9' {
+			p.i++
+		}
+		if p.i == e {
+			return nil, p.errorf("invalid number")
+		}
+	}
+	raw := p.expr[s:p.i]
+	num, _ := strconv.ParseFloat(raw, 64)
+	return jpLiteral{Result{Type: Number, Raw: raw, Num: num}}, nil
+}
+
+func (p *jpParser) parseFunc(name string, at int) (any, error) {
+	var nargs int
+	switch name {
+	case "length", "count", "value":
+		nargs = 1
+	case "match", "search":
+		nargs = 2
+	default:
+		p.i = at
+		return nil, p.errorf("unknown function %q", name)
+	}
+	p.eat('(')
+	f := &jpFunc{name: name}
+	for {
+		p.ws()
+		if p.peek() == ')' && len(f.args) == 0 {
+			break
+		}
+		argAt := p.i
+		arg, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "count", "value":
+			if _, ok := arg.(*jpQueryExpr); !ok {
+				p.i = argAt
+				return nil, p.errorf("function %q expects a query", name)
+			}
+		default:
+			if arg, err = p.comparable(arg, argAt); err != nil {
+				return nil, err
+			}
+		}
+		f.args = append(f.args, arg)
+		p.ws()
+		if !p.eat(',') {
+			break
+		}
+	}
+	if !p.eat(')') {
+		return nil, p.errorf("expected ')'")
+	}
+	if len(f.args) != nargs {
+		p.i = at
+		return nil, p.errorf("function %q expects %d arguments", name, nargs)
+	}
+	if f.logical() {
+		if lit, ok := f.args[1].(jpLiteral); ok && lit.v.Type == String {
+			re, err := compileIRegexp(lit.v.Str, name == "match")
+			if err != nil {
+				p.i = at
+				return nil, p.errorf("invalid regular expression")
+			}
+			f.re = re
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,213 @@
+package jj
+
+import (
+	"strings"
+	"testing"
+)
+
+const rfc9535Bookstore = `{ "store": {
+    "book": [
+      { "category": "reference",
+        "author": "Nigel Rees",
+        "title": "Sayings of the Century",
+        "price": 8.95
+      },
+      { "category": "fiction",
+        "author": "Evelyn Waugh",
+        "title": "Sword of Honour",
+        "price": 12.99
+      },
+      { "category": "fiction",
+        "author": "Herman Melville",
+        "title": "Moby Dick",
+        "isbn": "0-553-21311-3",
+        "price": 8.99
+      },
+      { "category": "fiction",
+        "author": "J. R. R. Tolkien",
+        "title": "The Lord of the Rings",
+        "isbn": "0-395-19395-8",
+        "price": 22.99
+      }
+    ],
+    "bicycle": {
+      "color": "red",
+      "price": 399
+    }
+  }
+}`
+
+const rfc9535Filter = `{
+  "a": [3, 5, 1, 2, 4, 6,
+        {"b": "j"},
+        {"b": "k"},
+        {"b": {}},
+        {"b": "kilo"}
+       ],
+  "o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}},
+  "e": "f"
+}`
+
+const rfc9535Descendant = `{
+  "o": {"j": 1, "k": 2},
+  "a": [5, 3, [{"j": 4}, {"k": 6}]]
+}`
+
+const rfc9535Null = `{"a": null, "b": [null], "c": [{}], "null": 1}`
+
+// jsonPathTests are drawn from the examples of RFC 9535.
+var jsonPathTests = []struct {
+	json   string
+	expr   string
+	expect string
+}{
+	// Table 2: example JSONPath expressions applied to the bookstore
+	{rfc9535Bookstore, `$.store.book[*].author`, `["Nigel Rees","Evelyn Waugh","Herman Melville","J. R. R. Tolkien"]`},
+	{rfc9535Bookstore, `$..author`, `["Nigel Rees","Evelyn Waugh","Herman Melville","J. R. R. Tolkien"]`},
+	{rfc9535Bookstore, `$.store.*`, `[[` +
+		`{"category":"reference","author":"Nigel Rees","title":"Sayings of the Century","price":8.95},` +
+		`{"category":"fiction","author":"Evelyn Waugh","title":"Sword of Honour","price":12.99},` +
+		`{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99},` +
+		`{"category":"fiction","author":"J. R. R. Tolkien","title":"The Lord of the Rings","isbn":"0-395-19395-8","price":22.99}` +
+		`],{"color":"red","price":399}]`},
+	{rfc9535Bookstore, `$.store..price`, `[8.95,12.99,8.99,22.99,399]`},
+	{rfc9535Bookstore, `$..book[2]`, `[{"category":"fiction","author":"Herman Melville","title":"Moby Dick","isbn":"0-553-21311-3","price":8.99}]`},
+	{rfc9535Bookstore, `$..book[2].author`, `["Herman Melville"]`},
+	{rfc9535Bookstore, `$..book[2].publisher`, `[]`},
+	{rfc9535Bookstore, `$..book[-1].title`, `["The Lord of the Rings"]`},
+	{rfc9535Bookstore, `$..book[0,1].title`, `["Sayings of the Century","Sword of Honour"]`},
+	{rfc9535Bookstore, `$..book[:2].title`, `["Sayings of the Century","Sword of Honour"]`},
+	{rfc9535Bookstore, `$..book[?@.isbn].title`, `["Moby Dick","The Lord of the Rings"]`},
+	{rfc9535Bookstore, `$..book[?@.price<10].title`, `["Sayings of the Century","Moby Dick"]`},
+	{rfc9535Bookstore, `$.store.book[?@.price < 10].title`, `["Sayings of the Century","Moby Dick"]`},
+	{rfc9535Bookstore, `$..*`, ""}, // checked by count below
+
+	// 2.3.1.3: name selector
+	{`{"o": {"j j": {"k.k": 3}}, "'": {"@": 2}}`, `$.o['j j']`, `[{"k.k": 3}]`},
+	{`{"o": {"j j": {"k.k": 3}}, "'": {"@": 2}}`, `$.o['j j']['k.k']`, `[3]`},
+	{`{"o": {"j j": {"k.k": 3}}, "'": {"@": 2}}`, `$.o["j j"]["k.k"]`, `[3]`},
+	{`{"o": {"j j": {"k.k": 3}}, "'": {"@": 2}}`, `$["'"]["@"]`, `[2]`},
+
+	// 2.3.2.3: wildcard selector
+	{`{"o": {"j": 1, "k": 2}, "a": [5, 3]}`, `$[*]`, `[{"j": 1, "k": 2},[5, 3]]`},
+	{`{"o": {"j": 1, "k": 2}, "a": [5, 3]}`, `$.o[*]`, `[1,2]`},
+	{`{"o": {"j": 1, "k": 2}, "a": [5, 3]}`, `$.o[*, *]`, `[1,2,1,2]`},
+	{`{"o": {"j": 1, "k": 2}, "a": [5, 3]}`, `$.a[*]`, `[5,3]`},
+
+	// 2.3.3.3: index selector
+	{`["a","b"]`, `$[1]`, `["b"]`},
+	{`["a","b"]`, `$[-2]`, `["a"]`},
+
+	// 2.3.4.3: array slice selector
+	{`["a", "b", "c", "d", "e", "f", "g"]`, `$[1:3]`, `["b","c"]`},
+	{`["a", "b", "c", "d", "e", "f", "g"]`, `$[5:]`, `["f","g"]`},
+	{`["a", "b", "c", "d", "e", "f", "g"]`, `$[1:5:2]`, `["b","d"]`},
+	{`["a", "b", "c", "d", "e", "f", "g"]`, `$[5:1:-2]`, `["f","d"]`},
+	{`["a", "b", "c", "d", "e", "f", "g"]`, `$[::-1]`, `["g","f","e","d","c","b","a"]`},
+	{`["a", "b", "c", "d", "e", "f", "g"]`, `$[::0]`, `[]`},
+
+	// 2.3.5.3: filter selector
+	{rfc9535Filter, `$.a[?@.b == 'kilo']`, `[{"b": "kilo"}]`},
+	{rfc9535Filter, `$.a[?(@.b == 'kilo')]`, `[{"b": "kilo"}]`},
+	{rfc9535Filter, `$.a[?@>3.5]`, `[5,4,6]`},
+	{rfc9535Filter, `$.a[?@.b]`, `[{"b": "j"},{"b": "k"},{"b": {}},{"b": "kilo"}]`},
+	{rfc9535Filter, `$[?@.*]`, `[[3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}],` +
+		`{"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}]`},
+	{rfc9535Filter, `$[?@[?@.b]]`, `[[3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]]`},
+	{rfc9535Filter, `$.o[?@<3, ?@<3]`, `[1,2,1,2]`},
+	{rfc9535Filter, `$.a[?@<2 || @.b == "k"]`, `[1,{"b": "k"}]`},
+	{rfc9535Filter, `$.a[?match(@.b, "[jk]")]`, `[{"b": "j"},{"b": "k"}]`},
+	{rfc9535Filter, `$.a[?search(@.b, "[jk]")]`, `[{"b": "j"},{"b": "k"},{"b": "kilo"}]`},
+	{rfc9535Filter, `$.o[?@>1 && @<4]`, `[2,3]`},
+	{rfc9535Filter, `$.o[?@.u || @.x]`, `[{"u": 6}]`},
+	{rfc9535Filter, `$.a[?@.b == $.x]`, `[3,5,1,2,4,6]`},
+	{rfc9535Filter, `$.a[?@ == @]`, `[3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]`},
+	{rfc9535Filter, `$.a[?!@.b]`, `[3,5,1,2,4,6]`},
+
+	// 2.4: function extensions
+	{rfc9535Filter, `$.a[?length(@.b) == 4]`, `[{"b": "kilo"}]`},
+	{rfc9535Filter, `$[?length(@) > 5]`, `[[3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]]`},
+	{rfc9535Filter, `$[?count(@.*) == 5]`, `[{"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}]`},
+	{rfc9535Filter, `$[?value(@..u) == 6]`, `[{"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}]`},
+	{`[{"d":"1974-05-11"},{"d":"1974-05-1x"},{"d":"1974-05-111"}]`, `$[?match(@.d, "1974-05-..")].d`, `["1974-05-11","1974-05-1x"]`},
+	{`["a\nb","ab"]`, `$[?match(@, "a.b")]`, `[]`},
+
+	// 2.5.2.3: descendant segment
+	{rfc9535Descendant, `$..j`, `[1,4]`},
+	{rfc9535Descendant, `$..[0]`, `[5,{"j": 4}]`},
+	{rfc9535Descendant, `$..[*]`, `[{"j": 1, "k": 2},[5, 3, [{"j": 4}, {"k": 6}]],1,2,5,3,[{"j": 4}, {"k": 6}],{"j": 4},{"k": 6},4,6]`},
+	{rfc9535Descendant, `$..o`, `[{"j": 1, "k": 2}]`},
+	{rfc9535Descendant, `$.o..[*, *]`, `[1,2,1,2]`},
+	{rfc9535Descendant, `$.a..[0, 1]`, `[5,3,{"j": 4},{"k": 6}]`},
+
+	// 2.6.1: semantics of null
+	{rfc9535Null, `$.a`, `[null]`},
+	{rfc9535Null, `$.a[0]`, `[]`},
+	{rfc9535Null, `$.a.d`, `[]`},
+	{rfc9535Null, `$.b[0]`, `[null]`},
+	{rfc9535Null, `$.b[*]`, `[null]`},
+	{rfc9535Null, `$.b[?@]`, `[null]`},
+	{rfc9535Null, `$.b[?@==null]`, `[null]`},
+	{rfc9535Null, `$.c[?@.d==null]`, `[]`},
+	{rfc9535Null, `$.null`, `[1]`},
+
+	// the root node
+	{`  {"a":1}  `, `$`, `[{"a":1}]`},
+	{`{"é":1,"a\"b":2}`, `$["é", 'a"b']`, `[1,2]`},
+}
+
+func jsonPathString(res []Result) string {
+	raws := make([]string, len(res))
+	for i, r := range res {
+		raws[i] = r.Raw
+	}
+	return "[" + strings.Join(raws, ",") + "]"
+}
+
+func TestQueryJSONPath(t *testing.T) {
+	for _, tt := range jsonPathTests {
+		res := QueryJSONPath(tt.json, tt.expr)
+		if res == nil {
+			if _, err := CompileJSONPath(tt.expr); err != nil {
+				t.Fatalf("%s: %v", tt.expr, err)
+			}
+		}
+		if tt.expect != "" {
+			expect := string(Ugly([]byte(tt.expect)))
+			if got := string(Ugly([]byte(jsonPathString(res)))); got != expect {
+				t.Fatalf("%s: expected '%v', got '%v'", tt.expr, expect, got)
+			}
+		}
+		// every node must be found at its own path
+		for _, r := range res {
+			path := r.Path(tt.json)
+			if path == "@this" {
+				continue
+			}
+			if got := Get(tt.json, path); got.Raw != r.Raw || got.Index != r.Index {
+				t.Fatalf("%s: path '%v' gives '%v', expected '%v'", tt.expr, path, got.Raw, r.Raw)
+			}
+		}
+	}
+	assert(t, len(QueryJSONPath(rfc9535Bookstore, `$..*`)) == 27)
+}
+
+func TestCompileJSONPathErrors(t *testing.T) {
+	for _, expr := range []string{
+		``, `store`, `$.`, `$[`, `$[1`, `$['a'`, `$[01]`, `$[-0]`, `$..`, `$.1a`,
+		`$[?@.a == ]`, `$[?@.* == 1]`, `$[?1]`, `$[?foo(@)]`, `$[?length(@.*) == 1]`,
+		`$[?count(1) == 1]`, `$[?match(@.a) ]`, `$[?match(@.a, "[")]`,
+		`$[?match(@.a, "x") == true]`, `$[?length(@.a)]`, `$[?!@.a == 1]`,
+		`$['\x']`, `$["\ud800"]`, `$[?(@.a]`, `$ x`, `$[?@.b == {}]`,
+	} {
+		if _, err := CompileJSONPath(expr); err == nil {
+			t.Fatalf("%s: expected an error", expr)
+		}
+		if QueryJSONPath(`{}`, expr) != nil {
+			t.Fatalf("%s: expected nil", expr)
+		}
+	}
+	_, err := CompileJSONPath(`$.a[?@.b = 1]`)
+	jerr, ok := err.(*JSONPathError)
+	assert(t, ok && jerr.Offset == 9)
+}
@@ -0,0 +1,151 @@
+package jj
+
+import "strconv"
+
+// ArrayStrategy is how DeepMerge merges two arrays.
+type ArrayStrategy int
+
+const (
+	// ArrayReplace replaces the array with the new one.
+	ArrayReplace ArrayStrategy = iota
+	// ArrayAppend appends the elements of the new array.
+	ArrayAppend
+	// ArrayMergeByKey merges the elements that have the same MergeOptions
+	// ArrayKey, and appends the others.
+	ArrayMergeByKey
+)
+
+// MergeOptions are the options of the DeepMerge function.
+type MergeOptions struct {
+	Arrays ArrayStrategy
+	// ArrayKey is the path of the key of the elements of the arrays, like
+	// "id", for ArrayMergeByKey.
+	ArrayKey string
+}
+
+// MergePatch applies a RFC 7386 JSON Merge Patch to target. The members of
+// the patch replace the ones of the target, the objects are merged, and
+// the null members delete the ones of the target. A patch that is not an
+// object replaces the whole target.
+//
+// The key order and the format of the target are kept, and the new keys
+// come after the others, as Set adds them.
+//
+//	jj.MergePatch([]byte(`{"a":"b","c":{"d":"e","f":"g"}}`), []byte(`{"a":"z","c":{"f":null}}`))
+//	// {"a":"z","c":{"d":"e"}}
+func MergePatch(target, patch []byte) ([]byte, error) {
+	m := merger{patch: true}
+	return m.mergeBytes(target, patch)
+}
+
+// DeepMerge merges the json b into a. The objects are merged member by
+// member, and the arrays by the strategy of the options, which replaces
+// them by default. The other values of b replace the ones of a, and unlike
+// in MergePatch a null is a value too.
+//
+// The key order and the format of a are kept, and the new keys come after
+// the others, as Set adds them.
+//
+//	jj.DeepMerge([]byte(`{"users":[{"id":1,"n":"a"}]}`), []byte(`{"users":[{"id":1,"age":3},{"id":2}]}`),
+//		jj.MergeOptions{Arrays: jj.ArrayMergeByKey, ArrayKey: "id"})
+//	// {"users":[{"id":1,"n":"a","age":3},{"id":2}]}
+func DeepMerge(a, b []byte, options ...MergeOptions) ([]byte, error) {
+	var m merger
+	if len(options) > 0 {
+		m.opts = options[0]
+	}
+	return m.mergeBytes(a, b)
+}
+
+type merger struct {
+	opts  MergeOptions
+	patch bool // RFC 7386, where a null deletes
+}
+
+func (m *merger) mergeBytes(a, b []byte) ([]byte, error) {
+	if !ValidBytes(a) || !ValidBytes(b) {
+		return nil, ErrInvalidJSON
+	}
+	json, err := m.merge(ParseBytes(a), ParseBytes(b))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(json), nil
+}
+
+// merge returns the raw json of b merged into a.
+func (m *merger) merge(a, b Result) (string, error) {
+	switch {
+	case a.IsObject() && b.IsObject():
+		return m.mergeObjects(a.Raw, b)
+	case m.patch && b.IsObject():
+		// the nulls of a new object are removed too
+		return m.mergeObjects("{}", b)
+	case !m.patch && a.IsArray() && b.IsArray():
+		return m.mergeArrays(a.Raw, b)
+	}
+	return b.Raw, nil
+}
+
+func (m *merger) mergeObjects(json string, b Result) (string, error) {
+	var err error
+	b.ForEach(func(key, value Result) bool {
+		pointer := string(appendPointerToken(nil, key.Str))
+		if m.patch && value.Type == Null {
+			json, err = DeletePointer(json, pointer)
+			return err == nil
+		}
+		var raw string
+		if raw, err = m.merge(pointerChild(Parse(json), key.Str), value); err != nil {
+			return false
+		}
+		json, err = SetRawPointer(json, pointer, raw)
+		return err == nil
+	})
+	return json, err
+}
+
+func (m *merger) mergeArrays(json string, b Result) (string, error) {
+	if m.opts.Arrays == ArrayReplace {
+		return b.Raw, nil
+	}
+	var err error
+	b.ForEach(func(_, value Result) bool {
+		i := -1
+		if m.opts.Arrays == ArrayMergeByKey {
+			i = m.indexByKey(Parse(json), value)
+		}
+		if i < 0 {
+			json, err = patchAdd(json, "/-", value.Raw)
+			return err == nil
+		}
+		pointer := "/" + strconv.Itoa(i)
+		var raw string
+		if raw, err = m.merge(GetPointer(json, pointer), value); err != nil {
+			return false
+		}
+		json, err = SetRawPointer(json, pointer, raw)
+		return err == nil
+	})
+	return json, err
+}
+
+// indexByKey returns the index of the element of arr that has the same key
+// as value, or -1 when there is none.
+func (m *merger) indexByKey(arr, value Result) int {
+	key := value.Get(m.opts.ArrayKey)
+	if !key.Exists() {
+		return -1
+	}
+	d := differ{opts: DiffOptions{IgnoreKeyOrder: true, NumericNumbers: true}}
+	index, i := -1, 0
+	arr.ForEach(func(_, elem Result) bool {
+		if d.equal(key, elem.Get(m.opts.ArrayKey)) {
+			index = i
+			return false
+		}
+		i++
+		return true
+	})
+	return index
+}
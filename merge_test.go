@@ -0,0 +1,73 @@
+package jj
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target, patch, expect string
+	}{
+		// the examples of RFC 7386, appendix A
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		// the format and the key order of the target are kept
+		{"{\n  \"b\": 1,\n  \"a\": {\"x\": [1, 2], \"y\": 2}\n}", `{"a":{"y":3,"z":{"n":null}},"c":"d"}`,
+			"{\n  \"b\": 1,\n  \"a\": {\"x\": [1, 2], \"y\": 3,\"z\":{}},\n  \"c\": \"d\"\n}"},
+		{`{"0":{"1":1}}`, `{"0":{"1":null,"2":2}}`, `{"0":{"2":2}}`},
+		{`{"a/b":{"c~d":1}}`, `{"a/b":{"c~d":2}}`, `{"a/b":{"c~d":2}}`},
+	}
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(tt.target), []byte(tt.patch))
+		if err != nil || string(got) != tt.expect {
+			t.Fatalf("patch '%v': expected '%v', got '%v' %v", tt.patch, tt.expect, string(got), err)
+		}
+	}
+	_, err := MergePatch([]byte(`{`), []byte(`{}`))
+	assert(t, errors.Is(err, ErrInvalidJSON))
+}
+
+func TestDeepMerge(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		opts   MergeOptions
+		expect string
+	}{
+		{`{"a":1,"b":{"c":1}}`, `{"b":{"d":2},"e":null}`, MergeOptions{}, `{"a":1,"b":{"c":1,"d":2},"e":null}`},
+		{`{"a":1}`, `{"a":null}`, MergeOptions{}, `{"a":null}`},
+		{`{"a":[1,2]}`, `{"a":[3]}`, MergeOptions{}, `{"a":[3]}`},
+		{`{"a":[1, 2]}`, `{"a":[3,{"b":1}]}`, MergeOptions{Arrays: ArrayAppend}, `{"a":[1, 2,3,{"b":1}]}`},
+		{`{"a":[]}`, `{"a":[3]}`, MergeOptions{Arrays: ArrayAppend}, `{"a":[3]}`},
+		{
+			`{"users":[{"id":1,"n":"a","tags":["x"]},{"id":2,"n":"b"}]}`,
+			`{"users":[{"id":2,"n":"B"},{"id":3},{"id":1.0,"tags":["y"]},{"n":"c"}]}`,
+			MergeOptions{Arrays: ArrayMergeByKey, ArrayKey: "id"},
+			`{"users":[{"id":1.0,"n":"a","tags":["x","y"]},{"id":2,"n":"B"},{"id":3},{"n":"c"}]}`,
+		},
+		{`[{"k":"a","v":1}]`, `[{"k":"a","v":2}]`, MergeOptions{Arrays: ArrayMergeByKey, ArrayKey: "k"}, `[{"k":"a","v":2}]`},
+		{`{"a":{"b":1}}`, `{"a":[1]}`, MergeOptions{}, `{"a":[1]}`},
+		{`1`, `{"a":1}`, MergeOptions{}, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		got, err := DeepMerge([]byte(tt.a), []byte(tt.b), tt.opts)
+		if err != nil || string(got) != tt.expect {
+			t.Fatalf("merge '%v' '%v': expected '%v', got '%v' %v", tt.a, tt.b, tt.expect, string(got), err)
+		}
+	}
+	_, err := DeepMerge([]byte(`{}`), []byte(`[`))
+	assert(t, errors.Is(err, ErrInvalidJSON))
+}
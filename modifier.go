@@ -0,0 +1,171 @@
+package jj
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ModifierFunc is a modifier that may fail. The arg is the parsed argument
+// of the modifier, which does not exist when there is none. An argument
+// that is not json, like the name of @sort:name, is a String.
+//
+// A failing modifier makes the result of the path not exist, and its error
+// is returned by the ModifierRegistry.Get method.
+type ModifierFunc func(json string, arg Result) (string, error)
+
+// ModifierError is the error of a modifier in a path.
+type ModifierError struct {
+	Name string
+	Err  error
+}
+
+func (e *ModifierError) Error() string {
+	return "modifier @" + e.Name + ": " + e.Err.Error()
+}
+
+func (e *ModifierError) Unwrap() error {
+	return e.Err
+}
+
+// modifier is either a plain modifier or a ModifierFunc.
+type modifier struct {
+	fn  func(json, arg string) string
+	fnE ModifierFunc
+}
+
+func (m modifier) exec(json, arg string) (string, error) {
+	if m.fn != nil {
+		return m.fn(json, arg), nil
+	}
+	return m.fnE(json, modifierArg(arg))
+}
+
+// modifierArg parses the argument of a modifier.
+func modifierArg(arg string) Result {
+	arg = trim(arg)
+	if arg == "" {
+		return Result{}
+	}
+	if Valid(arg) {
+		return Parse(arg)
+	}
+	return Result{Type: String, Str: arg, Raw: string(AppendJSONString(nil, arg))}
+}
+
+// ModifierRegistry is a set of modifiers for the '@name' path syntax. It
+// is safe for concurrent use, and modifiers can be added while other
+// goroutines get paths.
+//
+// The DefaultModifiers registry backs AddModifier, and is used by the paths
+// without the WithModifiers option. A registry of its own for each tenant
+// of a process keeps their modifiers apart:
+//
+//	r := jj.NewModifierRegistry()
+//	r.AddE("tenant", func(json string, arg jj.Result) (string, error) { ... })
+//	jj.Get(json, "@tenant", jj.WithModifiers(r))
+type ModifierRegistry struct {
+	mu   sync.Mutex // serializes the writers
+	mods atomic.Value
+}
+
+// DefaultModifiers is the registry of the modifiers of Get and Set.
+var DefaultModifiers = &ModifierRegistry{}
+
+// NewModifierRegistry returns a registry holding the built-in modifiers,
+// like @reverse and @pretty.
+func NewModifierRegistry() *ModifierRegistry {
+	r := &ModifierRegistry{}
+	r.mods.Store(builtinModifiers())
+	return r
+}
+
+func (r *ModifierRegistry) load() map[string]modifier {
+	m, _ := r.mods.Load().(map[string]modifier)
+	return m
+}
+
+// update copies the modifiers, so that the readers never see a map that is
+// being changed.
+func (r *ModifierRegistry) update(fn func(m map[string]modifier)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old := r.load()
+	m := make(map[string]modifier, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	fn(m)
+	r.mods.Store(m)
+}
+
+func (r *ModifierRegistry) lookup(name string) (modifier, bool) {
+	m, ok := r.load()[name]
+	return m, ok
+}
+
+// Add binds a modifier to the '@name' path syntax.
+func (r *ModifierRegistry) Add(name string, fn func(json, arg string) string) {
+	r.update(func(m map[string]modifier) { m[name] = modifier{fn: fn} })
+}
+
+// AddE binds a modifier that may fail to the '@name' path syntax.
+func (r *ModifierRegistry) AddE(name string, fn ModifierFunc) {
+	r.update(func(m map[string]modifier) { m[name] = modifier{fnE: fn} })
+}
+
+// Remove unbinds a modifier.
+func (r *ModifierRegistry) Remove(name string) {
+	r.update(func(m map[string]modifier) { delete(m, name) })
+}
+
+// Exists returns true when the modifier exists.
+func (r *ModifierRegistry) Exists(name string) bool {
+	_, ok := r.lookup(name)
+	return ok
+}
+
+// Clone returns a new registry with the same modifiers.
+func (r *ModifierRegistry) Clone() *ModifierRegistry {
+	c := &ModifierRegistry{}
+	c.update(func(m map[string]modifier) {
+		for k, v := range r.load() {
+			m[k] = v
+		}
+	})
+	return c
+}
+
+// Get searches json for the specified path with the modifiers of the
+// registry, like Get with the WithModifiers option does. It also returns
+// the first error of a modifier, as a *ModifierError.
+func (r *ModifierRegistry) Get(json, path string, optionsFns ...PathOptionFn) (Result, error) {
+	var err error
+	option := GetOptionFns(optionsFns).Apply(&PathOption{})
+	option.Modifiers = r
+	option.modErr = &err
+	res := getPath(json, path, option)
+	res.useNumber = option.UseNumber
+	return res, err
+}
+
+// WithModifiers sets the registry of the modifiers of a path, instead of
+// the DefaultModifiers.
+func WithModifiers(r *ModifierRegistry) PathOptionFn {
+	return func(o *PathOption) {
+		o.Modifiers = r
+	}
+}
+
+// modifiers returns the registry of the option.
+func (o *PathOption) modifiers() *ModifierRegistry {
+	if o != nil && o.Modifiers != nil {
+		return o.Modifiers
+	}
+	return DefaultModifiers
+}
+
+// nested returns the option for the paths that follow a part of a path,
+// like after a pipe, which keep the modifiers only.
+func (o *PathOption) nested() *PathOption {
+	return &PathOption{Modifiers: o.Modifiers, UseNumber: o.UseNumber, modErr: o.modErr}
+}
@@ -0,0 +1,155 @@
+package jj
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestModifierRegistry(t *testing.T) {
+	json := `{"name":"Tom","friends":[{"name":"Dale"},{"name":"Jane"}]}`
+	upper := func(json, arg string) string {
+		return strings.ToUpper(json)
+	}
+	a := NewModifierRegistry()
+	a.Add("shout", upper)
+	b := NewModifierRegistry()
+
+	assert(t, a.Exists("shout") && a.Exists("reverse"))
+	assert(t, !b.Exists("shout") && b.Exists("reverse"))
+	assert(t, !ModifierExists("shout", nil))
+
+	tests := []struct {
+		path   string
+		mods   *ModifierRegistry
+		expect string
+	}{
+		{`name|@shout`, a, `"TOM"`},
+		{`name|@shout`, b, ``},
+		{`name|@shout`, nil, ``},
+		{`@shout|name`, a, ``},
+		{`@shout|NAME`, a, `"TOM"`},
+		{`friends.#.name|@shout`, a, `["DALE","JANE"]`},
+		{`friends.#.@shout`, a, `[{"NAME":"DALE"},{"NAME":"JANE"}]`},
+		{`friends.@reverse|0.name|@shout`, a, `"JANE"`},
+		{`friends.#(name=="Jane")|@shout`, a, `{"NAME":"JANE"}`},
+		{`[name,friends.0.name|@shout]`, a, `["Tom","DALE"]`},
+		{`**.@shout`, a, `["TOM",[{"NAME":"DALE"},{"NAME":"JANE"}],{"NAME":"DALE"},"DALE",{"NAME":"JANE"},"JANE"]`},
+		{`**.@shout`, b, `[]`},
+	}
+	for _, tt := range tests {
+		got := Get(json, tt.path, WithModifiers(tt.mods)).Raw
+		if got != tt.expect {
+			t.Fatalf("path '%v': expected '%v', got '%v'", tt.path, tt.expect, got)
+		}
+		if got := CompilePath(tt.path, WithModifiers(tt.mods)).Get(json).Raw; got != tt.expect {
+			t.Fatalf("compiled path '%v': expected '%v', got '%v'", tt.path, tt.expect, got)
+		}
+	}
+
+	c := a.Clone()
+	c.Remove("shout")
+	assert(t, a.Exists("shout") && !c.Exists("shout") && c.Exists("pretty"))
+}
+
+func TestModifierFunc(t *testing.T) {
+	json := `{"nums":[1,2,3],"name":"Tom"}`
+	errNotArray := errors.New("not an array")
+	r := NewModifierRegistry()
+	r.AddE("take", func(json string, arg Result) (string, error) {
+		res := Parse(json)
+		if !res.IsArray() {
+			return "", errNotArray
+		}
+		n := int(arg.Int())
+		if !arg.Exists() {
+			n = 1
+		}
+		var out []string
+		for i, v := range res.Array() {
+			if i == n {
+				break
+			}
+			out = append(out, v.Raw)
+		}
+		return "[" + strings.Join(out, ",") + "]", nil
+	})
+	r.AddE("arg", func(json string, arg Result) (string, error) {
+		return arg.Raw, nil
+	})
+
+	tests := []struct {
+		path   string
+		expect string
+		err    bool
+	}{
+		{`nums|@take`, `[1]`, false},
+		{`nums|@take:2`, `[1,2]`, false},
+		{`nums|@take:{"n":1}`, `[]`, false},
+		{`nums|@take:2|#`, `2`, false},
+		{`name|@take`, ``, true},
+		{`name|@take|#`, ``, true},
+		{`[nums|@take,name|@take]`, `[[1]]`, true},
+		{`@arg:foo`, `"foo"`, false},
+		{`@arg:[1, 2]`, `[1, 2]`, false},
+		{`@arg`, ``, false},
+	}
+	for _, tt := range tests {
+		res, err := r.Get(json, tt.path)
+		if res.Raw != tt.expect || (err != nil) != tt.err {
+			t.Fatalf("path '%v': expected '%v' %v, got '%v' %v", tt.path, tt.expect, tt.err, res.Raw, err)
+		}
+		if err != nil {
+			var me *ModifierError
+			assert(t, errors.As(err, &me) && me.Name == "take" && errors.Is(err, errNotArray))
+		}
+		p := CompilePath(tt.path, WithModifiers(r))
+		if got := p.Get(json); got.Raw != tt.expect {
+			t.Fatalf("compiled path '%v': expected '%v', got '%v'", tt.path, tt.expect, got.Raw)
+		}
+		if _, err := p.GetE(json); tt.err {
+			assert(t, errors.Is(err, errNotArray))
+		}
+	}
+	assert(t, Get(json, `name|@take`, WithModifiers(r)).Raw == ``)
+
+	// the output of a failing modifier is dropped
+	r.AddE("partial", func(json string, arg Result) (string, error) {
+		return `[1]`, errNotArray
+	})
+	for _, path := range []string{`@partial`, `nums|@partial|0`, `{"a":@partial}`} {
+		res, err := r.Get(json, path)
+		assert(t, (res.Raw == "" || res.Raw == "{}") && errors.Is(err, errNotArray))
+		p := CompilePath(path, WithModifiers(r))
+		assert(t, p.Get(json).Raw == res.Raw)
+		res, err = p.GetE(json)
+		assert(t, !res.Exists() && errors.Is(err, errNotArray))
+	}
+	res, err := CompilePath(`nums|@take:2`, WithModifiers(r)).GetE(json)
+	assert(t, err == nil && res.Raw == `[1,2]`)
+}
+
+func TestModifierRegistryConcurrent(t *testing.T) {
+	r := NewModifierRegistry()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				r.Add("this2", modThis)
+				r.Remove("this2")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if Get(`[1,2]`, `@reverse|0`, WithModifiers(r)).Raw != "2" {
+					panic("bad result")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
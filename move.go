@@ -0,0 +1,134 @@
+package jj
+
+// Rename renames the key of the object member at the path to the new key.
+// The member keeps its position, and the other bytes of the json are kept
+// as they are. Renaming to the key of another member of the same object is
+// an ErrConflict error.
+//
+//	jj.Rename(`{"user":{"fullname":"Tom","age":37}}`, "user.fullname", "name")
+//	// {"user":{"name":"Tom","age":37}}
+func Rename(json, path, newKey string, options ...SetOptions) (string, error) {
+	var opts SetOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	res := Get(json, path, ApplyGetOption(opts.PathOption), DisableNegativeIndex(true))
+	if !res.Exists() {
+		return json, &SetError{Err: ErrPathNotFound, Path: path}
+	}
+	start, end, ok := memberKey(json, res)
+	if !ok {
+		return json, &SetError{Err: ErrInvalidPath, Path: path, Reason: "path is not an object member"}
+	}
+	if Parse(json[start:end]).Str == newKey {
+		return json, nil
+	}
+	if comps, ok := res.pathComps(json); ok {
+		parent := Parse(json)
+		if len(comps) > 1 {
+			var ppath string
+			for _, comp := range comps[:len(comps)-1] {
+				ppath = joinPath(ppath, comp)
+			}
+			parent = Get(json, ppath)
+		}
+		var exists bool
+		parent.ForEach(func(key, _ Result) bool {
+			exists = key.Str == newKey
+			return !exists
+		})
+		if exists {
+			return json, &SetError{Err: ErrConflict, Path: path, Reason: "key " + string(AppendJSONString(nil, newKey)) + " already exists"}
+		}
+	}
+	return json[:start] + string(AppendJSONString(nil, newKey)) + json[end:], nil
+}
+
+// memberKey returns the span of the key of the object member whose value is
+// the result, or false when the result is not the value of a member.
+func memberKey(json string, res Result) (start, end int, ok bool) {
+	if res.Index <= 0 || res.Index+len(res.Raw) > len(json) || json[res.Index:res.Index+len(res.Raw)] != res.Raw {
+		return 0, 0, false
+	}
+	i := res.Index - 1
+	for i >= 0 && json[i] <= ' ' {
+		i--
+	}
+	if i < 0 || json[i] != ':' {
+		return 0, 0, false
+	}
+	for i--; i >= 0 && json[i] <= ' '; i-- {
+	}
+	if i < 0 || json[i] != '"' {
+		return 0, 0, false
+	}
+	end = i + 1
+	for i--; i >= 0; i-- {
+		if json[i] != '"' {
+			continue
+		}
+		// the quote is escaped when it follows an odd number of backslashes
+		n := 0
+		for j := i - 1; j >= 0 && json[j] == '\\'; j-- {
+			n++
+		}
+		if n%2 == 0 {
+			return i, end, true
+		}
+	}
+	return 0, 0, false
+}
+
+// Move moves the value at the from path to the to path. The value is
+// deleted first, so the to path is a path of the json without it, and is
+// set like with SetRaw. Both paths must be paths of one value, without
+// wildcards, queries or modifiers, and the value cannot be moved into
+// itself, which are ErrInvalidPath errors.
+//
+//	jj.Move(`{"user":{"name":"Tom"},"tags":[]}`, "user.name", "tags.0")
+//	// {"user":{},"tags":["Tom"]}
+func Move(json, from, to string, options ...SetOptions) (string, error) {
+	var opts SetOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	sc := opts.config()
+	if _, simple := parsePaths(from, sc); !simple {
+		return json, &SetError{Err: ErrInvalidPath, Path: from, Reason: "cannot move the value of a path with wildcards, queries or modifiers"}
+	}
+	tos, simple := parsePaths(to, sc)
+	if !simple {
+		return json, &SetError{Err: ErrInvalidPath, Path: to, Reason: "cannot move a value to a path with wildcards, queries or modifiers"}
+	}
+	res := Get(json, from, ApplyGetOption(opts.PathOption), DisableNegativeIndex(true))
+	if !res.Exists() {
+		return json, &SetError{Err: ErrPathNotFound, Path: from}
+	}
+	// resolve the to path in the json, to find whether it is the value or a
+	// path inside it
+	var ppath string
+	for i, p := range tos {
+		if i > 0 {
+			ppath += "."
+		}
+		ppath += p.gpart
+		cur := Get(json, ppath, ApplyGetOption(opts.PathOption), DisableNegativeIndex(true))
+		if !cur.Exists() {
+			break
+		}
+		if cur.Index == res.Index && len(cur.Raw) == len(res.Raw) {
+			if i == len(tos)-1 {
+				return json, nil
+			}
+			return json, &SetError{Err: ErrInvalidPath, Path: to, Reason: "cannot move a value into itself"}
+		}
+	}
+	moved, err := Delete(json, from, opts)
+	if err != nil {
+		return json, err
+	}
+	if moved, err = SetRaw(moved, to, res.Raw, opts); err != nil {
+		return json, err
+	}
+	return moved, nil
+}
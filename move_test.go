@@ -0,0 +1,65 @@
+package jj
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRename(t *testing.T) {
+	json := `{"user":{"fullname": "Tom", "age":37,"tags":["a"]}, "x\"y":1}`
+	tests := []struct {
+		path, key, expect string
+	}{
+		{"user.fullname", "name", `{"user":{"name": "Tom", "age":37,"tags":["a"]}, "x\"y":1}`},
+		{"user.tags", "a\"b", `{"user":{"fullname": "Tom", "age":37,"a\"b":["a"]}, "x\"y":1}`},
+		{`x\"y`, "z", `{"user":{"fullname": "Tom", "age":37,"tags":["a"]}, "z":1}`},
+		{"user", "u", `{"u":{"fullname": "Tom", "age":37,"tags":["a"]}, "x\"y":1}`},
+		{"user.age", "age", json},
+	}
+	for _, tt := range tests {
+		got, err := Rename(json, tt.path, tt.key)
+		if err != nil || got != tt.expect {
+			t.Fatalf("path '%v': expected '%v', got '%v' %v", tt.path, tt.expect, got, err)
+		}
+	}
+	_, err := Rename(json, "user.age", "fullname")
+	assert(t, errors.Is(err, ErrConflict))
+	_, err = Rename(json, "user.missing", "name")
+	assert(t, errors.Is(err, ErrPathNotFound))
+	_, err = Rename(json, "user.tags.0", "name")
+	assert(t, errors.Is(err, ErrInvalidPath))
+}
+
+func TestMove(t *testing.T) {
+	json := `{"user":{"fullname":"Tom","age":37},"tags":["a","b"]}`
+	tests := []struct {
+		from, to, expect string
+	}{
+		{"user.fullname", "user.name", `{"user":{"age":37,"name":"Tom"},"tags":["a","b"]}`},
+		{"user.age", "age", `{"user":{"fullname":"Tom"},"tags":["a","b"],"age":37}`},
+		{"tags.0", "tags.-1", `{"user":{"fullname":"Tom","age":37},"tags":["b","a"]}`},
+		{"user", "tags.[+0]", `{"tags":[{"fullname":"Tom","age":37},"a","b"]}`},
+		{"tags", "tags", json},
+	}
+	for _, tt := range tests {
+		got, err := Move(json, tt.from, tt.to)
+		if err != nil || got != tt.expect {
+			t.Fatalf("path '%v': expected '%v', got '%v' %v", tt.from, tt.expect, got, err)
+		}
+	}
+	got, err := Move(json, "user.missing", "name")
+	assert(t, errors.Is(err, ErrPathNotFound) && got == json)
+	for _, to := range []string{"user.me", "user.fullname.x"} {
+		got, err = Move(json, "user", to)
+		assert(t, errors.Is(err, ErrInvalidPath) && got == json)
+	}
+	got, err = Move(json, "tags.0", "tags.0.x")
+	assert(t, errors.Is(err, ErrInvalidPath) && got == json)
+
+	// the paths must be paths of one value
+	json = `{"a":{"x":1,"y":2}}`
+	for _, tt := range [][2]string{{"a.*", "b"}, {"a", "a|c"}, {"a.x", "b.#"}, {"a|x", "b"}, {"a.@this", "b"}} {
+		got, err = Move(json, tt[0], tt[1])
+		assert(t, errors.Is(err, ErrInvalidPath) && got == json)
+	}
+}
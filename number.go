@@ -0,0 +1,231 @@
+package jj
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxNumberExp is the largest exponent of a number that the exact
+// accessors accept, which keeps a number like 1e999999999 from
+// allocating its digits.
+const maxNumberExp = 1 << 16
+
+// splitNumber splits a number into its sign, its digits without the leading
+// zeros, and the position of the decimal point in the digits, which may be
+// before or after them. The number 12.50 is "1250" with the point at 2, and
+// 0.05 is "5" with the point at -1.
+func splitNumber(s string) (neg bool, digits string, point int, ok bool) {
+	var i int
+	if i < len(s) && s[i] == '-' {
+		neg = true
+		i++
+	}
+	start := i
+	for ; i < len(s) && isnum(s[i]); i++ {
+	}
+	intPart := s[start:i]
+	var frac string
+	if i < len(s) && s[i] == '.' {
+		i++
+		start = i
+		for ; i < len(s) && isnum(s[i]); i++ {
+		}
+		frac = s[start:i]
+	}
+	if intPart == "" && frac == "" {
+		return false, "", 0, false
+	}
+	var exp int
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		var eneg bool
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			eneg = s[i] == '-'
+			i++
+		}
+		if i == len(s) {
+			return false, "", 0, false
+		}
+		for ; i < len(s) && isnum(s[i]); i++ {
+			if exp = exp*10 + int(s[i]-'0'); exp > maxNumberExp {
+				return false, "", 0, false
+			}
+		}
+		if eneg {
+			exp = -exp
+		}
+	}
+	if i != len(s) {
+		return false, "", 0, false
+	}
+	digits = intPart + frac
+	point = len(intPart) + exp
+	for len(digits) > 0 && digits[0] == '0' {
+		digits = digits[1:]
+		point--
+	}
+	return neg, digits, point, true
+}
+
+// numberText returns the text of a Number, or of a String holding a number.
+func (t Result) numberText() (string, bool) {
+	switch t.Type {
+	case Number:
+		if len(t.Raw) == 0 {
+			// calculated result
+			return strconv.FormatFloat(t.Num, 'g', -1, 64), true
+		}
+		return t.Raw, true
+	case String:
+		return t.Str, true
+	}
+	return "", false
+}
+
+// bigInt returns the integer part of the number, and if the number has no
+// fraction.
+func (t Result) bigInt() (n *big.Int, exact, ok bool) {
+	s, ok := t.numberText()
+	if !ok {
+		return nil, false, false
+	}
+	neg, digits, point, ok := splitNumber(s)
+	if !ok {
+		return nil, false, false
+	}
+	n = new(big.Int)
+	exact = true
+	if point > 0 {
+		intDigits := digits
+		if point < len(digits) {
+			intDigits = digits[:point]
+		} else if point > len(digits) {
+			intDigits += strings.Repeat("0", point-len(digits))
+		}
+		n.SetString(intDigits, 10)
+	}
+	if point < len(digits) {
+		exact = strings.Trim(digits[max(point, 0):], "0") == ""
+	}
+	if neg {
+		n.Neg(n)
+	}
+	return n, exact, true
+}
+
+// BigInt returns the integer part of a number without the loss of
+// precision of Int, like for the ids beyond 2^53 or beyond 64 bits.
+// A String holding a number is converted too. It returns zero for the
+// other values, like Int does.
+func (t Result) BigInt() *big.Int {
+	if t.Type == True {
+		return big.NewInt(1)
+	}
+	n, _, ok := t.bigInt()
+	if !ok {
+		return new(big.Int)
+	}
+	return n
+}
+
+// BigFloat returns a number as a big.Float with a precision that holds all
+// of its digits. A String holding a number is converted too. It returns
+// zero for the other values, like Float does.
+func (t Result) BigFloat() *big.Float {
+	f := new(big.Float)
+	if t.Type == True {
+		return f.SetInt64(1)
+	}
+	s, ok := t.numberText()
+	if !ok {
+		return f
+	}
+	neg, digits, point, ok := splitNumber(s)
+	if !ok || digits == "" {
+		return f
+	}
+	f.SetPrec(uint(max(64, 4*len(digits))))
+	f.SetString(digits + "e" + strconv.Itoa(point-len(digits)))
+	if neg {
+		f.Neg(f)
+	}
+	return f
+}
+
+// Decimal returns a number as an exact decimal string without an exponent,
+// like "1500" for 1.5e3 and "0.10" for 0.10. The digits are never rounded,
+// so the scale of amounts like 12.50 is kept. A String holding a number is
+// converted too. It returns "0" for the other values, like Int does.
+func (t Result) Decimal() string {
+	if t.Type == True {
+		return "1"
+	}
+	s, ok := t.numberText()
+	if !ok {
+		return "0"
+	}
+	neg, digits, point, ok := splitNumber(s)
+	if !ok {
+		return "0"
+	}
+	var b []byte
+	if neg && digits != "" {
+		b = append(b, '-')
+	}
+	switch fracN := len(digits) - point; {
+	case point <= 0:
+		b = append(b, '0')
+		if fracN > 0 {
+			b = append(b, '.')
+			b = append(b, strings.Repeat("0", -point)...)
+			b = append(b, digits...)
+		}
+	case fracN <= 0:
+		b = append(b, digits...)
+		b = append(b, strings.Repeat("0", -fracN)...)
+	default:
+		b = append(b, digits[:point]...)
+		b = append(b, '.')
+		b = append(b, digits[point:]...)
+	}
+	return string(b)
+}
+
+// Int64OK returns the same value as Int, and whether it is exact. The ok is
+// false when the value is not a number, or a String holding a number, or
+// when the number has a fraction or does not fit into an int64.
+func (t Result) Int64OK() (int64, bool) {
+	if t.Type == Number && len(t.Raw) > 0 && len(t.Raw) < 19 {
+		if n, ok := parseInt(t.Raw); ok {
+			return n, true
+		}
+	}
+	n, exact, ok := t.bigInt()
+	if !ok {
+		return t.Int(), false
+	}
+	if !n.IsInt64() {
+		return t.Int(), false
+	}
+	return n.Int64(), exact
+}
+
+// Uint64OK returns the same value as Uint, and whether it is exact. The ok
+// is false when the value is not a number, or a String holding a number, or
+// when the number has a fraction or does not fit into an uint64.
+func (t Result) Uint64OK() (uint64, bool) {
+	if t.Type == Number && len(t.Raw) > 0 && len(t.Raw) < 20 {
+		if n, ok := parseUint(t.Raw); ok {
+			return n, true
+		}
+	}
+	n, exact, ok := t.bigInt()
+	if !ok {
+		return t.Uint(), false
+	}
+	if !n.IsUint64() {
+		return t.Uint(), false
+	}
+	return n.Uint64(), exact
+}
@@ -0,0 +1,97 @@
+package jj
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestBigNumbers(t *testing.T) {
+	tests := []struct {
+		json    string
+		bigInt  string
+		decimal string
+	}{
+		{`634866135153775564.88172`, "634866135153775564", "634866135153775564.88172"},
+		{`123456789012345678901234567890`, "123456789012345678901234567890", "123456789012345678901234567890"},
+		{`-2.9`, "-2", "-2.9"},
+		{`1.5e3`, "1500", "1500"},
+		{`1.50E+1`, "15", "15.0"},
+		{`12.50`, "12", "12.50"},
+		{`0.05`, "0", "0.05"},
+		{`5e-3`, "0", "0.005"},
+		{`-0.00`, "0", "0.00"},
+		{`0`, "0", "0"},
+		{`"99999999999999999999"`, "99999999999999999999", "99999999999999999999"},
+		{`"1.0x"`, "0", "0"},
+		{`true`, "1", "1"},
+		{`null`, "0", "0"},
+		{`1e99999999`, "0", "0"},
+	}
+	for _, tt := range tests {
+		res := Parse(tt.json)
+		if got := res.BigInt().String(); got != tt.bigInt {
+			t.Fatalf("json '%v': expected '%v', got '%v'", tt.json, tt.bigInt, got)
+		}
+		if got := res.Decimal(); got != tt.decimal {
+			t.Fatalf("json '%v': expected '%v', got '%v'", tt.json, tt.decimal, got)
+		}
+	}
+	f := Parse(`123456789012345678901234567890.5`).BigFloat()
+	assert(t, f.Text('f', 1) == "123456789012345678901234567890.5")
+	assert(t, Parse(`-0.1`).BigFloat().Text('g', 10) == "-0.1")
+	assert(t, Parse(`"x"`).BigFloat().Sign() == 0)
+	n, _ := new(big.Int).SetString("18446744073709551616", 10)
+	assert(t, Get(`{"a":[18446744073709551616]}`, "a.0").BigInt().Cmp(n) == 0)
+}
+
+func TestInt64OK(t *testing.T) {
+	tests := []struct {
+		json string
+		i    int64
+		iok  bool
+		u    uint64
+		uok  bool
+	}{
+		{`9007199254740993`, 9007199254740993, true, 9007199254740993, true},
+		{`-9223372036854775808`, -9223372036854775808, true, 0, false},
+		{`9223372036854775808`, -9223372036854775808, false, 9223372036854775808, true},
+		{`18446744073709551615`, 0, false, 18446744073709551615, true},
+		{`18446744073709551616`, 0, false, 0, false},
+		{`1.5`, 1, false, 1, false},
+		{`1.0`, 1, true, 1, true},
+		{`1e2`, 100, true, 100, true},
+		{`"42"`, 42, true, 42, true},
+		{`"x"`, 0, false, 0, false},
+		{`true`, 1, false, 1, false},
+	}
+	for _, tt := range tests {
+		res := Parse(tt.json)
+		i, iok := res.Int64OK()
+		u, uok := res.Uint64OK()
+		if iok != tt.iok || uok != tt.uok || (iok && i != tt.i) || (uok && u != tt.u) {
+			t.Fatalf("json '%v': expected '%v %v %v %v', got '%v %v %v %v'",
+				tt.json, tt.i, tt.iok, tt.u, tt.uok, i, iok, u, uok)
+		}
+	}
+}
+
+func TestUseNumber(t *testing.T) {
+	js := `{"amount":12345678901234567.89,"items":[{"price":1.10}],"n":1}`
+	v := Parse(js, WithUseNumber(true)).Value()
+	expect := map[string]interface{}{
+		"amount": json.Number("12345678901234567.89"),
+		"items":  []interface{}{map[string]interface{}{"price": json.Number("1.10")}},
+		"n":      json.Number("1"),
+	}
+	assert(t, reflect.DeepEqual(v, expect))
+	assert(t, Get(js, "amount", WithUseNumber(true)).Value() == json.Number("12345678901234567.89"))
+	assert(t, Get(js, "items", WithUseNumber(true)).Get("0.price").Value() == json.Number("1.10"))
+	assert(t, ParseBytes([]byte(js), WithUseNumber(true)).Get("n").Value() == json.Number("1"))
+	Get(js, "items.0", WithUseNumber(true)).ForEach(func(_, value Result) bool {
+		assert(t, value.Value() == json.Number("1.10"))
+		return true
+	})
+	assert(t, Parse(js).Get("n").Value() == float64(1))
+}
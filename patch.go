@@ -0,0 +1,229 @@
+package jj
+
+import "strconv"
+
+// ErrTestFailed is the error of a JSON Patch "test" operation whose value
+// is not the expected one.
+var ErrTestFailed = &errorType{"test failed"}
+
+// PatchError is the error of ApplyPatch, with the failing operation.
+type PatchError struct {
+	// Index is the position of the operation in the patch.
+	Index int
+	Op    string
+	Path  string
+	Err   error
+}
+
+func (e *PatchError) Error() string {
+	return "patch operation " + strconv.Itoa(e.Index) + " (" + e.Op + " " + strconv.Quote(e.Path) + "): " + e.Err.Error()
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyPatch applies a RFC 6902 JSON Patch to doc, with the "add",
+// "remove", "replace", "move", "copy" and "test" operations. The paths are
+// RFC 6901 JSON Pointers.
+//
+// The patch is atomic: when an operation fails, the doc is returned
+// unchanged with a *PatchError that tells the index of the operation. The
+// missing values are reported with ErrPathNotFound, and a failing test with
+// ErrTestFailed.
+//
+//	doc, err := jj.ApplyPatch(doc, []byte(`[
+//		{"op":"test","path":"/version","value":3},
+//		{"op":"replace","path":"/version","value":4},
+//		{"op":"add","path":"/tags/-","value":"rolled"}
+//	]`))
+func ApplyPatch(doc, patch []byte) ([]byte, error) {
+	if !ValidBytes(doc) {
+		return doc, ErrInvalidJSON
+	}
+	ops := ParseBytes(patch)
+	if !ValidBytes(patch) || !ops.IsArray() {
+		return doc, &errorType{"patch must be an array of operations"}
+	}
+	json := string(doc)
+	var err error
+	var index int
+	ops.ForEach(func(_, op Result) bool {
+		name, path := op.Get("op").String(), op.Get("path").String()
+		if json, err = applyPatchOp(json, op); err != nil {
+			err = &PatchError{Index: index, Op: name, Path: path, Err: err}
+			return false
+		}
+		index++
+		return true
+	})
+	if err != nil {
+		return doc, err
+	}
+	return []byte(json), nil
+}
+
+func applyPatchOp(json string, op Result) (string, error) {
+	path := op.Get("path")
+	if path.Type != String {
+		return json, &errorType{"missing path"}
+	}
+	value := op.Get("value")
+	switch name := op.Get("op").String(); name {
+	case "add", "replace", "test":
+		if !value.Exists() {
+			return json, &errorType{"missing value"}
+		}
+		switch name {
+		case "add":
+			return patchAdd(json, path.Str, value.Raw)
+		case "replace":
+			if _, err := patchTarget(json, path.Str); err != nil {
+				return json, err
+			}
+			return SetRawPointer(json, path.Str, value.Raw)
+		}
+		target, err := patchTarget(json, path.Str)
+		if err != nil {
+			return json, err
+		}
+		d := differ{opts: DiffOptions{IgnoreKeyOrder: true, NumericNumbers: true}}
+		if !d.equal(target, value) {
+			return json, ErrTestFailed
+		}
+		return json, nil
+	case "remove":
+		if _, err := patchTarget(json, path.Str); err != nil {
+			return json, err
+		}
+		return DeletePointer(json, path.Str)
+	case "move", "copy":
+		from := op.Get("from")
+		if from.Type != String {
+			return json, &errorType{"missing from"}
+		}
+		source, err := patchTarget(json, from.Str)
+		if err != nil {
+			return json, err
+		}
+		if name == "move" {
+			if from.Str == path.Str {
+				return json, nil
+			}
+			if isPointerPrefix(from.Str, path.Str) {
+				return json, &errorType{"cannot move a value into itself"}
+			}
+			if json, err = DeletePointer(json, from.Str); err != nil {
+				return json, err
+			}
+		}
+		return patchAdd(json, path.Str, source.Raw)
+	default:
+		return json, &errorType{"unknown op '" + name + "'"}
+	}
+}
+
+// patchTarget returns the value at the pointer, which must exist.
+func patchTarget(json, pointer string) (Result, error) {
+	if _, err := parsePointer(pointer); err != nil {
+		return Result{}, err
+	}
+	res := GetPointer(json, pointer)
+	if !res.Exists() {
+		return res, ErrPathNotFound
+	}
+	return res, nil
+}
+
+// isPointerPrefix returns true when the pointer is inside the prefix one.
+func isPointerPrefix(prefix, pointer string) bool {
+	return len(pointer) > len(prefix) && pointer[:len(prefix)] == prefix && pointer[len(prefix)] == '/'
+}
+
+// patchAdd adds a value to an object, or inserts it into an array before
+// the element at the index, and its parent must exist.
+func patchAdd(json, pointer, raw string) (string, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return json, err
+	}
+	if len(tokens) == 0 {
+		return raw, nil
+	}
+	var parentPointer []byte
+	for _, tok := range tokens[:len(tokens)-1] {
+		parentPointer = appendPointerToken(parentPointer, tok)
+	}
+	parent := GetPointer(json, string(parentPointer))
+	switch {
+	case parent.IsObject():
+		return SetRawPointer(json, pointer, raw)
+	case !parent.IsArray():
+		return json, ErrPathNotFound
+	}
+	elems := parent.Array()
+	n := len(elems)
+	if tok := tokens[len(tokens)-1]; tok != "-" {
+		var ok bool
+		if n, ok = pointerIndex(tok); !ok || n > len(elems) {
+			return json, &errorType{"invalid array index '" + tok + "'"}
+		}
+	}
+	// splice the value into the json, which keeps its format
+	next := ","
+	if l, ok := multilineLayout(parent); ok {
+		next = l.next
+	}
+	switch {
+	case n < len(elems):
+		i := elems[n].Index
+		return json[:i] + raw + next + json[i:], nil
+	case n > 0:
+		i := elems[n-1].Index + len(elems[n-1].Raw)
+		return json[:i] + next + raw + json[i:], nil
+	default:
+		i := parent.Index + 1
+		return json[:i] + raw + json[i:], nil
+	}
+}
+
+// CreatePatch returns a RFC 6902 JSON Patch that turns the json a into b,
+// made of "add", "remove" and "replace" operations on the values that are
+// different. The order of the keys and the formats of the numbers are not
+// differences. The equal elements of the arrays are kept even when they
+// move, like the longest common subsequence of a line diff, so that
+// removing the first element is one "remove"; the arrays too big for it
+// are compared element by element.
+//
+//	jj.CreatePatch([]byte(`{"a":1,"b":[1,2]}`), []byte(`{"a":2,"b":[1]}`))
+//	// [{"op":"replace","path":"/a","value":2},{"op":"remove","path":"/b/1"}]
+func CreatePatch(a, b []byte) ([]byte, error) {
+	if !ValidBytes(a) || !ValidBytes(b) {
+		return nil, ErrInvalidJSON
+	}
+	d := differ{opts: DiffOptions{IgnoreKeyOrder: true, NumericNumbers: true}, pointer: true}
+	d.diff("", ParseBytes(a), ParseBytes(b))
+	patch := []byte{'['}
+	for i, c := range d.changes {
+		if i > 0 {
+			patch = append(patch, ',')
+		}
+		patch = append(patch, `{"op":`...)
+		switch c.Type {
+		case Added:
+			patch = append(patch, `"add"`...)
+		case Removed:
+			patch = append(patch, `"remove"`...)
+		case Changed:
+			patch = append(patch, `"replace"`...)
+		}
+		patch = append(patch, `,"path":`...)
+		patch = AppendJSONString(patch, c.Path)
+		if c.Type != Removed {
+			patch = append(patch, `,"value":`...)
+			patch = append(patch, Ugly([]byte(c.New))...)
+		}
+		patch = append(patch, '}')
+	}
+	return append(patch, ']'), nil
+}
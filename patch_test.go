@@ -0,0 +1,111 @@
+package jj
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		doc, patch, expect string
+	}{
+		// the examples of RFC 6902, appendix A
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"foo":"bar","baz":"qux"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{
+			`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"foo":"bar","baz":"qux"}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		// more
+		{`{"a":[ ]}`, `[{"op":"add","path":"/a/0","value":1},{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2 ]}`},
+		{`{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`},
+		{`{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+		{`{"a":1}`, `[{"op":"move","from":"/a","path":"/a"}]`, `{"a":1}`},
+		{`{"a":{"x":1,"y":[1.0]}}`, `[{"op":"test","path":"/a","value":{"y":[1],"x":1}}]`, `{"a":{"x":1,"y":[1.0]}}`},
+		{`{"a":1}`, `[]`, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		got, err := ApplyPatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil || string(got) != tt.expect {
+			t.Fatalf("patch '%v': expected '%v', got '%v' %v", tt.patch, tt.expect, string(got), err)
+		}
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	doc := `{"foo":"bar","arr":[1,2]}`
+	tests := []struct {
+		patch  string
+		index  int
+		expect error
+	}{
+		{`[{"op":"add","path":"/a","value":1},{"op":"remove","path":"/baz"}]`, 1, ErrPathNotFound},
+		{`[{"op":"replace","path":"/baz","value":1}]`, 0, ErrPathNotFound},
+		{`[{"op":"add","path":"/baz/bat","value":"qux"}]`, 0, ErrPathNotFound},
+		{`[{"op":"test","path":"/foo","value":"baz"}]`, 0, ErrTestFailed},
+		{`[{"op":"test","path":"/arr","value":[2,1]}]`, 0, ErrTestFailed},
+		{`[{"op":"move","from":"/missing","path":"/foo"}]`, 0, ErrPathNotFound},
+		{`[{"op":"add","path":"/arr/3","value":3}]`, 0, nil},
+		{`[{"op":"add","path":"/arr/01","value":3}]`, 0, nil},
+		{`[{"op":"remove","path":"/arr/-"}]`, 0, ErrPathNotFound},
+		{`[{"op":"move","from":"/arr","path":"/arr/0"}]`, 0, nil},
+		{`[{"op":"add","path":"foo","value":1}]`, 0, nil},
+		{`[{"op":"add","path":"/x"}]`, 0, nil},
+		{`[{"op":"copy","path":"/x"}]`, 0, nil},
+		{`[{"op":"remove"}]`, 0, nil},
+		{`[{"op":"frob","path":"/x"}]`, 0, nil},
+	}
+	for _, tt := range tests {
+		got, err := ApplyPatch([]byte(doc), []byte(tt.patch))
+		var pe *PatchError
+		if !errors.As(err, &pe) || pe.Index != tt.index || string(got) != doc {
+			t.Fatalf("patch '%v': expected an error at %v, got '%v' %v", tt.patch, tt.index, string(got), err)
+		}
+		if tt.expect != nil && !errors.Is(err, tt.expect) {
+			t.Fatalf("patch '%v': expected '%v', got '%v'", tt.patch, tt.expect, err)
+		}
+	}
+	_, err := ApplyPatch([]byte(doc), []byte(`{"op":"add"}`))
+	assert(t, err != nil)
+	_, err = ApplyPatch([]byte(`{`), []byte(`[]`))
+	assert(t, errors.Is(err, ErrInvalidJSON))
+	_, err = ApplyPatch([]byte(doc), []byte(`[{"op":"remove","path":"/a"}]`))
+	assert(t, err.Error() == `patch operation 0 (remove "/a"): path not found`)
+}
+
+func TestCreatePatch(t *testing.T) {
+	tests := []struct {
+		a, b, expect string
+	}{
+		{`{"a":1}`, `{"a":1.0}`, `[]`},
+		{`{"a":1,"b":[1,2]}`, `{"a":2,"b":[1]}`, `[{"op":"replace","path":"/a","value":2},{"op":"remove","path":"/b/1"}]`},
+		{`{"a":[1,2,3,4]}`, `{"a":[0]}`, `[{"op":"replace","path":"/a/0","value":0},{"op":"remove","path":"/a/3"},{"op":"remove","path":"/a/2"},{"op":"remove","path":"/a/1"}]`},
+		{`{"a/b":{"c~":1}}`, `{"a/b":{"c~":{"d": [1]}},"e":null}`, `[{"op":"replace","path":"/a~1b/c~0","value":{"d":[1]}},{"op":"add","path":"/e","value":null}]`},
+		{`[]`, `[1,2]`, `[{"op":"add","path":"/0","value":1},{"op":"add","path":"/1","value":2}]`},
+		{`1`, `{}`, `[{"op":"replace","path":"","value":{}}]`},
+		// the equal elements of the arrays are kept
+		{`[1,2,3]`, `[2,3]`, `[{"op":"remove","path":"/0"}]`},
+		{`[1,2,3]`, `[0,1,2,3]`, `[{"op":"add","path":"/0","value":0}]`},
+		{`{"a":[1,{"b":2},3,4]}`, `{"a":[0,1,{"b":5},4,7]}`, `[{"op":"add","path":"/a/0","value":0},{"op":"replace","path":"/a/2/b","value":5},{"op":"remove","path":"/a/3"},{"op":"add","path":"/a/4","value":7}]`},
+	}
+	for _, tt := range tests {
+		got, err := CreatePatch([]byte(tt.a), []byte(tt.b))
+		if err != nil || string(got) != tt.expect {
+			t.Fatalf("patch '%v' '%v': expected '%v', got '%v' %v", tt.a, tt.b, tt.expect, string(got), err)
+		}
+		patched, err := ApplyPatch([]byte(tt.a), got)
+		assert(t, err == nil && Diff(patched, []byte(tt.b), DiffOptions{IgnoreKeyOrder: true, NumericNumbers: true}) == nil)
+	}
+	_, err := CreatePatch([]byte(`{`), []byte(`{}`))
+	assert(t, errors.Is(err, ErrInvalidJSON))
+}
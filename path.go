@@ -0,0 +1,390 @@
+package jj
+
+// Path is a compiled GJSON path. The path syntax is parsed once by
+// CompilePath, and the parsed components, queries, modifiers and
+// sub-selectors are reused by every evaluation, which makes it suitable
+// for hot paths that are evaluated over and over again.
+//
+// A Path is safe for concurrent use by multiple goroutines.
+//
+//	p := jj.CompilePath("friends.#(last=Murphy)#.first")
+//	for _, json := range docs {
+//		println(p.Get(json).String())
+//	}
+type Path struct {
+	path   string
+	option PathOption
+
+	mod    *modifierPath
+	static *staticPath
+	sel    *selectorPath
+
+	lines bool
+	step  *pathStep
+}
+
+// modifierPath is a compiled '@modifier:args' path head.
+type modifierPath struct {
+	name string
+	args string
+	fn   modifier
+	next *Path // path following the modifier, nil when there is none
+}
+
+// staticPath is a compiled '!literal' path head.
+type staticPath struct {
+	raw  string
+	next *Path // path following the literal, nil when there is none
+}
+
+// selectorPath is a compiled '[path1,path2]' or '{"a":path1}' sub-selector.
+type selectorPath struct {
+	kind byte
+	subs []selectorSub
+	next *Path // path following the selector, nil when there is none
+}
+
+type selectorSub struct {
+	key  []byte // the json key and colon, only for object selectors
+	path *Path
+}
+
+// pathStep holds the parsed form of one component of a path, both as an
+// object key and as an array index, as the parser decides between them by
+// the json it meets.
+type pathStep struct {
+	obj     objectPathResult
+	arr     arrayPathResult
+	objMore *pathStep
+	arrMore *pathStep
+	objPipe *Path
+	arrPipe *Path
+
+	query  *Path // the path inside a #(...) query
+	qmore  *Path // the path following a matched query
+	qpipe  *Path // the piped part following a matched query
+	qpiped bool
+
+	alog      *Path // the path following a '#.' or a slice
+	alogPipe  *Path // the piped part following a '#.' or a slice
+	alogPiped bool
+
+	deep      *Path // the path following a '**.'
+	deepPipe  *Path // the piped part following a '**.'
+	deepPiped bool
+}
+
+func (s *pathStep) objNext() *pathStep {
+	if s == nil {
+		return nil
+	}
+	return s.objMore
+}
+
+func (s *pathStep) arrNext() *pathStep {
+	if s == nil {
+		return nil
+	}
+	return s.arrMore
+}
+
+func (s *pathStep) objPipePath() *Path {
+	if s == nil {
+		return nil
+	}
+	return s.objPipe
+}
+
+func (s *pathStep) arrPipePath() *Path {
+	if s == nil {
+		return nil
+	}
+	return s.arrPipe
+}
+
+// CompilePath parses a GJSON path for later, repeated, use.
+// The options, like WithRawPath and DisableNegativeIndex, are applied at
+// compile time. The returned Path produces the same results as calling
+// Get with the same path and options.
+//
+// The DisableModifiers setting and the set of modifiers in use are
+// captured when the path is compiled.
+func CompilePath(path string, optionsFns ...PathOptionFn) *Path {
+	option := GetOptionFns(optionsFns).Apply(&PathOption{})
+	return compilePath(path, *option)
+}
+
+func compilePath(path string, option PathOption) *Path {
+	c := &pathCompiler{option: option, steps: make(map[string]*pathStep)}
+	return c.compile(path)
+}
+
+type pathCompiler struct {
+	option PathOption
+	steps  map[string]*pathStep
+}
+
+// compile follows the same decisions as Get does, in the same order.
+func (pc *pathCompiler) compile(path string) *Path {
+	p := &Path{path: path, option: pc.option}
+	if !pc.option.RawPath && len(path) > 1 {
+		if path[0] == '@' && !DisableModifiers {
+			mods := pc.option.modifiers()
+			name, args, npath := parseModifier(path, mods)
+			if fn, ok := mods.lookup(name); ok {
+				p.mod = &modifierPath{name: name, args: args, fn: fn, next: compileNext(npath, pc.option)}
+			}
+		} else if path[0] == '!' {
+			if npath, raw, ok := execStatic("", path); ok {
+				p.static = &staticPath{raw: raw, next: compileNext(npath, pc.option)}
+			}
+		}
+		if (path[0] == '[' && !isSlice(path)) || path[0] == '{' {
+			kind := path[0]
+			var ok bool
+			var subs []subSelector
+			subs, path, ok = parseSubSelectors(path)
+			if ok && (len(path) == 0 || (path[0] == '|' || path[0] == '.')) {
+				p.sel = compileSelector(kind, subs, path, pc.option)
+				return p
+			}
+		}
+	}
+	if !pc.option.RawPath && len(path) >= 2 && path[0] == '.' && path[1] == '.' {
+		p.lines = true
+		path = path[2:]
+	}
+	p.step = pc.step(path)
+	return p
+}
+
+// compileNext compiles the path remaining after a modifier or a static value.
+func compileNext(path string, option PathOption) *Path {
+	if len(path) > 0 && (path[0] == '|' || path[0] == '.') {
+		return compilePath(path[1:], *option.nested())
+	}
+	return nil
+}
+
+func compileSelector(kind byte, subs []subSelector, path string, option PathOption) *selectorPath {
+	sel := &selectorPath{kind: kind}
+	for _, sub := range subs {
+		var key []byte
+		if kind == '{' {
+			if len(sub.name) > 0 {
+				if sub.name[0] == '"' && Valid(sub.name) {
+					key = append(key, sub.name...)
+				} else {
+					key = AppendJSONString(key, sub.name)
+				}
+			} else {
+				last := nameOfLast(sub.path)
+				if isSimpleName(last) {
+					key = AppendJSONString(key, last)
+				} else {
+					key = AppendJSONString(key, "_")
+				}
+			}
+			key = append(key, ':')
+		}
+		sel.subs = append(sel.subs, selectorSub{key: key, path: compilePath(sub.path, *option.nested())})
+	}
+	if len(path) > 0 {
+		sel.next = compilePath(path[1:], *option.nested())
+	}
+	return sel
+}
+
+func (pc *pathCompiler) step(path string) *pathStep {
+	if st, ok := pc.steps[path]; ok {
+		return st
+	}
+	st := &pathStep{obj: parseObjectPath(path, &pc.option), arr: parseArrayPath(path, &pc.option)}
+	pc.steps[path] = st
+	if st.obj.more {
+		st.objMore = pc.step(st.obj.path)
+	}
+	if st.obj.piped {
+		st.objPipe = compilePath(st.obj.pipe, *pc.option.nested())
+	}
+	if st.arr.more {
+		st.arrMore = pc.step(st.arr.path)
+	}
+	if st.arr.piped {
+		st.arrPipe = compilePath(st.arr.pipe, *pc.option.nested())
+	}
+	if st.arr.query.on {
+		st.query = compilePath(st.arr.query.path, *pc.option.nested())
+		if st.arr.more {
+			more := st.arr.path
+			if left, right, ok := splitPossiblePipe(more); ok {
+				more = left
+				st.qpipe = compilePath(right, *pc.option.nested())
+				st.qpiped = true
+			}
+			st.qmore = compilePath(more, *pc.option.nested())
+		}
+	}
+	if st.arr.alogok && (!st.arr.sliced || st.arr.alogkey != "") {
+		alog := st.arr.alogkey
+		if left, right, ok := splitPossiblePipe(alog); ok {
+			alog = left
+			st.alogPipe = compilePath(right, *pc.option.nested())
+			st.alogPiped = true
+		}
+		st.alog = compilePath(alog, *pc.option.nested())
+	}
+	if st.obj.wild && st.obj.part == "**" && st.obj.more {
+		deep := st.obj.path
+		if left, right, ok := splitPossiblePipe(deep); ok {
+			deep = left
+			st.deepPipe = compilePath(right, *pc.option.nested())
+			st.deepPiped = true
+		}
+		st.deep = compilePath(deep, *pc.option.nested())
+	}
+	return st
+}
+
+// String returns the path that was compiled.
+func (p *Path) String() string {
+	return p.path
+}
+
+// Get searches json for the compiled path.
+// See the Get function for details.
+func (p *Path) Get(json string) Result {
+	return p.get(json, nil)
+}
+
+// GetE searches json for the compiled path like the GetE function, and
+// tells why there is no value. The path is parsed again to find out where
+// it does not resolve.
+func (p *Path) GetE(json string) (Result, error) {
+	var modErr error
+	res := p.get(json, &modErr)
+	if modErr != nil {
+		return Result{}, modErr
+	}
+	if _, ok := validOffset(json, p.lines); ok && res.Exists() {
+		return res, nil
+	}
+	return GetE(json, p.path, func(o *PathOption) { *o = p.option })
+}
+
+// get is Get, which records the first error of a modifier in modErr when
+// it is not nil.
+func (p *Path) get(json string, modErr *error) Result {
+	if p.mod != nil {
+		res, err := p.mod.fn.exec(json, p.mod.args)
+		if err != nil {
+			modifierFailed(modErr, p.mod.name, err)
+			res = ""
+		}
+		return getNext(res, p.mod.next, modErr)
+	}
+	if p.static != nil {
+		return getNext(p.static.raw, p.static.next, modErr)
+	}
+	if p.sel != nil {
+		return p.sel.get(json, modErr)
+	}
+	c := &parseContext{json: json, modErr: modErr}
+	if p.lines {
+		c.lines = true
+		parseArray(c, 0, "", p.step, &p.option)
+	} else {
+		for i := 0; i < len(c.json); i++ {
+			if c.json[i] == '{' {
+				parseObject(c, i+1, "", p.step, &p.option)
+				break
+			}
+			if c.json[i] == '[' {
+				parseArray(c, i+1, "", p.step, &p.option)
+				break
+			}
+		}
+	}
+	if c.piped {
+		res := c.pipePath.getResult(c.value, modErr)
+		res.Index = 0
+		return res
+	}
+	fillIndex(json, c)
+	return c.value
+}
+
+func getNext(rjson string, next *Path, modErr *error) Result {
+	if next == nil {
+		return Parse(rjson)
+	}
+	res := next.get(rjson, modErr)
+	res.Index = 0
+	res.Indexes = nil
+	return res
+}
+
+func (sel *selectorPath) get(json string, modErr *error) Result {
+	var b []byte
+	b = append(b, sel.kind)
+	var i int
+	for _, sub := range sel.subs {
+		res := sub.path.get(json, modErr)
+		if res.Exists() {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, sub.key...)
+			var raw string
+			if len(res.Raw) == 0 {
+				raw = res.String()
+				if len(raw) == 0 {
+					raw = "null"
+				}
+			} else {
+				raw = res.Raw
+			}
+			b = append(b, raw...)
+			i++
+		}
+	}
+	b = append(b, sel.kind+2)
+	var res Result
+	res.Raw = string(b)
+	res.Type = JSON
+	if sel.next != nil {
+		res = sel.next.getResult(res, modErr)
+	}
+	res.Index = 0
+	return res
+}
+
+// getResult is the compiled counterpart of Result.Get.
+func (p *Path) getResult(t Result, modErr *error) Result {
+	r := p.get(t.Raw, modErr)
+	if r.Indexes != nil {
+		for i := 0; i < len(r.Indexes); i++ {
+			r.Indexes[i] += t.Index
+		}
+	} else {
+		r.Index += t.Index
+	}
+	return r
+}
+
+// GetBytes searches json for the compiled path.
+// If working with bytes, this method preferred over p.Get(string(data))
+func (p *Path) GetBytes(json []byte) Result {
+	return getBytesWith(json, p.Get)
+}
+
+// GetMany searches each of the json documents for the compiled path.
+// The return value is a Result array where the number of items
+// will be equal to the number of input documents.
+func (p *Path) GetMany(jsons ...string) []Result {
+	res := make([]Result, len(jsons))
+	for i, json := range jsons {
+		res[i] = p.Get(json)
+	}
+	return res
+}
@@ -0,0 +1,107 @@
+package jj
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+var compiledPathTests = []struct {
+	json  string
+	paths []string
+}{
+	{readmeJSON, []string{
+		"name.last", "age", "children", "children.#", "children.1",
+		"child*.2", "c?ildren.0", `fav\.movie`, "friends.#.first",
+		"friends.1.last", "friends.-1.first", "friends.#.nets.-1",
+		`friends.#(last=="Murphy").first`, `friends.#(last=="Murphy")#.first`,
+		`friends.#(first%"D*").last`, `friends.#(first!%"D*").last`,
+		`friends.#(nets.#(=="fb"))#.first`, `friends.#(age>45)#.last|#`,
+		`friends.#(age>45)#|0.first`, "friends.#.nets|@flatten",
+		"children|@reverse", "children|@reverse|0", "@this", "@keys",
+		"friends.@reverse.0.first", "friends.0.@keys",
+		`{name.first,age,"the_murphys":friends.#(last="Murphy")#.first}`,
+		`[name.first,age,children.0]`, `[name.first,age].1`,
+		`!true`, `!"static"|@tostr`, `{"a":!1}`, "name|first",
+		"friends.#.{first,age}", "friends|#", "nothing.here",
+		`@pretty:{"indent":"\t"}`, `children.@join`, "friends.#.nets.#",
+		"**.first", "friends.**.first", "**.nets|@flatten", "**.#(age>45)#.last",
+		"friends.**", `**.nets.#(=="tw")`, "friends.[1:3]", "friends.[-2:].first",
+		"children.[::-1]", "children.[:2]|#", "friends.[:2].nets|@flatten",
+	}},
+	{basicJSON, []string{
+		`loggy.programmers.#[tag="good"].firstName`,
+		`loggy.programmers.#[tag="good"]#.firstName`,
+		`loggy.programmers.#.firstName`, "items.3.tags.#", "items.3.points.1",
+		"arr.#", "arr.3.hello", `noop.what is a wren?`, `lastly.end\.\.\.ing`,
+		"name.last", "created", "vals.3.sadf",
+	}},
+	{exampleJSON, benchManyPaths},
+	{`{"a":1}` + "\n" + `{"a":2}` + "\n" + `{"a":3}`, []string{
+		"..#", "..0.a", "..#.a", "..#(a>1)#.a", "..-1.a", "..**.a", "..**", "..[1:]", "..[::-1].a",
+	}},
+}
+
+func TestCompilePath(t *testing.T) {
+	for _, tt := range compiledPathTests {
+		for _, path := range tt.paths {
+			p := CompilePath(path)
+			if p.String() != path {
+				t.Fatalf("expected '%v', got '%v'", path, p.String())
+			}
+			expect := Get(tt.json, path)
+			if got := p.Get(tt.json); !reflect.DeepEqual(expect, got) {
+				t.Fatalf("path '%v': expected %#v, got %#v", path, expect, got)
+			}
+			expect = GetBytes([]byte(tt.json), path)
+			if got := p.GetBytes([]byte(tt.json)); !reflect.DeepEqual(expect, got) {
+				t.Fatalf("path '%v': expected %#v, got %#v", path, expect, got)
+			}
+		}
+	}
+}
+
+func TestCompilePathOptions(t *testing.T) {
+	json := `{"a.b":1,"a":{"b":2},"arr":[1,2,3]}`
+	p := CompilePath("a.b", WithRawPath(true))
+	assert(t, p.Get(json).Int() == 1)
+	p = CompilePath("a.b")
+	assert(t, p.Get(json).Int() == 2)
+	for _, path := range []string{"arr.-1", "arr.-3", "arr.1"} {
+		for _, disable := range []bool{false, true} {
+			expect := Get(json, path, DisableNegativeIndex(disable))
+			got := CompilePath(path, DisableNegativeIndex(disable)).Get(json)
+			if !reflect.DeepEqual(expect, got) {
+				t.Fatalf("path '%v': expected %#v, got %#v", path, expect, got)
+			}
+		}
+	}
+}
+
+func TestCompilePathGetMany(t *testing.T) {
+	p := CompilePath("friends.#(last=Murphy)#.first")
+	res := p.GetMany(readmeJSON, `{"friends":[{"first":"Tom","last":"Murphy"}]}`, "")
+	assert(t, len(res) == 3)
+	assert(t, res[0].String() == `["Dale","Jane"]`)
+	assert(t, res[1].String() == `["Tom"]`)
+	assert(t, !res[2].Exists())
+}
+
+func TestCompilePathConcurrent(t *testing.T) {
+	p := CompilePath(`friends.#(nets.#(=="ig"))#.first|@reverse`)
+	expect := Get(readmeJSON, p.String()).Raw
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if got := p.Get(readmeJSON).Raw; got != expect {
+					t.Errorf("expected '%v', got '%v'", expect, got)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
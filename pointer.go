@@ -0,0 +1,240 @@
+package jj
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// parsePointer splits a RFC 6901 JSON Pointer into its unescaped reference
+// tokens. The URI fragment form, like "#/definitions/a%20b", is accepted
+// too. The empty pointer, which references the whole document, has no tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if len(pointer) > 0 && pointer[0] == '#' {
+		p, err := url.PathUnescape(pointer[1:])
+		if err != nil {
+			return nil, &errorType{"invalid pointer fragment '" + pointer + "'"}
+		}
+		pointer = p
+	}
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, &errorType{"pointer must start with '/'"}
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, tok := range tokens {
+		if strings.IndexByte(tok, '~') == -1 {
+			continue
+		}
+		var b []byte
+		for j := 0; j < len(tok); j++ {
+			if tok[j] != '~' {
+				b = append(b, tok[j])
+				continue
+			}
+			if j+1 < len(tok) && tok[j+1] == '0' {
+				b = append(b, '~')
+			} else if j+1 < len(tok) && tok[j+1] == '1' {
+				b = append(b, '/')
+			} else {
+				return nil, &errorType{"invalid escape in pointer token '" + tok + "'"}
+			}
+			j++
+		}
+		tokens[i] = string(b)
+	}
+	return tokens, nil
+}
+
+// pointerIndex converts a reference token into an array index. Only the
+// digits of a non-negative integer without leading zeros are an index.
+func pointerIndex(tok string) (int, bool) {
+	if tok == "" || (len(tok) > 1 && tok[0] == '0') {
+		return 0, false
+	}
+	for i := 0; i < len(tok); i++ {
+		if tok[i] < '0' || tok[i] > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(tok)
+	return n, err == nil
+}
+
+// pointerChild returns the member or element of node referenced by tok.
+func pointerChild(node Result, tok string) (child Result) {
+	if node.IsObject() {
+		node.ForEach(func(key, value Result) bool {
+			if key.Str == tok {
+				child = value
+				return false
+			}
+			return true
+		})
+	} else if node.IsArray() {
+		if n, ok := pointerIndex(tok); ok {
+			var i int
+			node.ForEach(func(_, value Result) bool {
+				if i == n {
+					child = value
+					return false
+				}
+				i++
+				return true
+			})
+		}
+	}
+	return child
+}
+
+// GetPointer searches json for the value referenced by a RFC 6901 JSON
+// Pointer, like "/friends/0/first" or "/a~1b/c~0d" for the "c~d" member of
+// the "a/b" member. Unlike the Get path syntax no character in a key needs
+// escaping other than '/' and '~'.
+//
+// The pointer may also be in the URI fragment form that is used by
+// OpenAPI and JSON Schema $ref values, like "#/components/schemas/Pet".
+//
+// The empty pointer "" references the whole json document. A Result with
+// the Exists() false is returned when the value does not exist or the
+// pointer is invalid.
+func GetPointer(json, pointer string) Result {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return Result{}
+	}
+	_, res, ok := parseAny(json, 0, true)
+	if !ok {
+		return Result{}
+	}
+	for _, tok := range tokens {
+		if res = pointerChild(res, tok); !res.Exists() {
+			break
+		}
+	}
+	return res
+}
+
+// SetPointer sets a json value for the location referenced by a RFC 6901
+// JSON Pointer. The "-" token appends to an array, like "/children/-".
+// Missing objects and arrays along the pointer are created the same way
+// as Set does.
+//
+// Setting the empty pointer "" replaces the whole document.
+func SetPointer(json, pointer string, value interface{}) (string, error) {
+	sc := makeSetConfig(false, false, false, false)
+	raw, err := valueRaw(value, &sc)
+	if err != nil {
+		return "", err
+	}
+	return setPointer(json, pointer, raw, sc)
+}
+
+// SetRawPointer sets a raw json value for the location referenced by a
+// RFC 6901 JSON Pointer. See SetPointer.
+func SetRawPointer(json, pointer, value string) (string, error) {
+	return setPointer(json, pointer, value, makeSetConfig(false, false, false, false))
+}
+
+// DeletePointer deletes the value referenced by a RFC 6901 JSON Pointer.
+// The json is returned unchanged when the value does not exist.
+func DeletePointer(json, pointer string) (string, error) {
+	return setPointer(json, pointer, "", makeSetConfig(false, true, false, false))
+}
+
+func setPointer(json, pointer, raw string, sc setConfig) (string, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return json, err
+	}
+	if len(tokens) == 0 {
+		if sc.del {
+			return json, &errorType{"cannot delete the whole document"}
+		}
+		if sc.stringify {
+			return string(appendStringify(nil, raw)), nil
+		}
+		return raw, nil
+	}
+
+	// Each token becomes a raw key. Whether it is an array index depends on
+	// the json that it meets, so walk the existing values alongside.
+	sc.RawPath = true
+	paths := make([]pathResult, len(tokens))
+	node, exists := Parse(json), true
+	for i, tok := range tokens {
+		r := pathResult{part: tok, gpart: tok, more: i < len(tokens)-1}
+		switch {
+		case exists && node.IsArray():
+			if tok == "-" {
+				if sc.del {
+					return json, nil
+				}
+				r.part = "-1"
+			} else if _, ok := pointerIndex(tok); !ok {
+				return json, &errorType{"invalid array index '" + tok + "' in pointer"}
+			}
+		case exists && node.IsObject():
+			// a member name, even when it looks like a number
+			r.force = true
+		case tok == "-":
+			r.part = "-1"
+		}
+		paths[i] = r
+		if exists {
+			node = pointerChild(node, tok)
+			exists = node.Exists()
+		}
+	}
+	if sc.del && !exists {
+		return json, nil
+	}
+	res, err := appendRawPaths(nil, json, paths, raw, sc)
+	if err == errNoChange {
+		return json, nil
+	}
+	if err != nil {
+		return json, err
+	}
+	return string(res), nil
+}
+
+// Pointer returns the RFC 6901 JSON Pointer of the Result, which is the
+// counterpart of Path.
+//
+// The json param must be the original JSON used when calling Get.
+//
+// Returns an empty string if the pointer cannot be determined, which is the
+// same as the pointer of the whole document. Like Path, this can happen when
+// the Result came from a path that contained a multipath, modifier, or a
+// nested query.
+func (t Result) Pointer(json string) string {
+	comps, ok := t.pathComps(json)
+	if !ok {
+		return ""
+	}
+	var b []byte
+	for _, comp := range comps {
+		b = appendPointerToken(b, comp)
+	}
+	return string(b)
+}
+
+// appendPointerToken appends a '/' and the escaped reference token of a key
+// or an index to a JSON Pointer.
+func appendPointerToken(b []byte, comp string) []byte {
+	b = append(b, '/')
+	for i := 0; i < len(comp); i++ {
+		switch comp[i] {
+		case '~':
+			b = append(b, '~', '0')
+		case '/':
+			b = append(b, '~', '1')
+		default:
+			b = append(b, comp[i])
+		}
+	}
+	return b
+}
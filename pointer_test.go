@@ -0,0 +1,149 @@
+package jj
+
+import "testing"
+
+// the example document of RFC 6901, section 5
+const pointerJSON = `{
+	"foo": ["bar", "baz"],
+	"": 0,
+	"a/b": 1,
+	"c%d": 2,
+	"e^f": 3,
+	"g|h": 4,
+	"i\\j": 5,
+	"k\"l": 6,
+	" ": 7,
+	"m~n": 8,
+	"a.b*": {"#": 9}
+}`
+
+func TestGetPointer(t *testing.T) {
+	tests := []struct {
+		pointer string
+		raw     string
+	}{
+		{"", pointerJSON},
+		{"/foo", `["bar", "baz"]`},
+		{"/foo/0", `"bar"`},
+		{"/", `0`},
+		{"/a~1b", `1`},
+		{"/c%d", `2`},
+		{"/e^f", `3`},
+		{"/g|h", `4`},
+		{"/i\\j", `5`},
+		{"/k\"l", `6`},
+		{"/ ", `7`},
+		{"/m~0n", `8`},
+		{"/a.b*/#", `9`},
+		{"#", pointerJSON},
+		{"#/foo", `["bar", "baz"]`},
+		{"#/foo/0", `"bar"`},
+		{"#/", `0`},
+		{"#/a~1b", `1`},
+		{"#/c%25d", `2`},
+		{"#/e%5Ef", `3`},
+		{"#/g%7Ch", `4`},
+		{"#/i%5Cj", `5`},
+		{"#/k%22l", `6`},
+		{"#/%20", `7`},
+		{"#/m~0n", `8`},
+		{"/foo/2", ``},
+		{"/foo/-", ``},
+		{"/foo/01", ``},
+		{"/foo/bar", ``},
+		{"/nothing", ``},
+		{"/m~2n", ``},
+		{"foo", ``},
+		{"#/%zz", ``},
+	}
+	for _, tt := range tests {
+		res := GetPointer(pointerJSON, tt.pointer)
+		if res.Raw != tt.raw {
+			t.Fatalf("pointer '%v': expected '%v', got '%v'", tt.pointer, tt.raw, res.Raw)
+		}
+		if res.Exists() {
+			assert(t, pointerJSON[res.Index:res.Index+len(res.Raw)] == res.Raw)
+		}
+	}
+}
+
+func TestResultPointer(t *testing.T) {
+	json := `{"a":{"b/c":[1,{"x~y":{"d.e":true}}]}}`
+	assert(t, Get(json, `a.b/c.1.x~y.d\.e`).Pointer(json) == "/a/b~1c/1/x~0y/d.e")
+	assert(t, Get(json, "a").Pointer(json) == "/a")
+	assert(t, GetPointer(json, "").Pointer(json) == "")
+	assert(t, Get(json, "a|@keys").Pointer(json) == "")
+	for _, ptr := range []string{"/a", "/a/b~1c", "/a/b~1c/0", "/a/b~1c/1/x~0y/d.e"} {
+		if got := GetPointer(json, ptr).Pointer(json); got != ptr {
+			t.Fatalf("expected '%v', got '%v'", ptr, got)
+		}
+	}
+	Get(readmeJSON, "friends").ForEach(func(_, value Result) bool {
+		value.ForEach(func(_, value Result) bool {
+			ptr := value.Pointer(readmeJSON)
+			assert(t, ptr != "" && GetPointer(readmeJSON, ptr).Raw == value.Raw)
+			return true
+		})
+		return true
+	})
+}
+
+func TestSetPointer(t *testing.T) {
+	json := `{"a":{"b/c":[1,2]},"0":"zero","d.e":{}}`
+	tests := []struct {
+		pointer string
+		value   interface{}
+		expect  string
+	}{
+		{"/a/b~1c/0", 10, `{"a":{"b/c":[10,2]},"0":"zero","d.e":{}}`},
+		{"/a/b~1c/-", "x", `{"a":{"b/c":[1,2,"x"]},"0":"zero","d.e":{}}`},
+		{"/0", true, `{"a":{"b/c":[1,2]},"0":true,"d.e":{}}`},
+		{"/d.e/f*", 1.5, `{"a":{"b/c":[1,2]},"0":"zero","d.e":{"f*":1.5}}`},
+		{"/n/-", "y", `{"a":{"b/c":[1,2]},"0":"zero","d.e":{},"n":["y"]}`},
+		{"/a/-", 1, `{"a":{"b/c":[1,2],"-":1},"0":"zero","d.e":{}}`},
+		{"", map[string]int{"z": 1}, `{"z":1}`},
+	}
+	for _, tt := range tests {
+		res, err := SetPointer(json, tt.pointer, tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res != tt.expect {
+			t.Fatalf("pointer '%v': expected '%v', got '%v'", tt.pointer, tt.expect, res)
+		}
+	}
+	res, err := SetRawPointer(json, "/a/b~1c/1", `{"k":[]}`)
+	assert(t, err == nil && res == `{"a":{"b/c":[1,{"k":[]}]},"0":"zero","d.e":{}}`)
+	for _, ptr := range []string{"a", "/a/b~1c/x", "/a/b~1c/01", "/a/~"} {
+		res, err := SetPointer(json, ptr, 1)
+		if err == nil || res != json {
+			t.Fatalf("pointer '%v': expected an error", ptr)
+		}
+	}
+}
+
+func TestDeletePointer(t *testing.T) {
+	json := `{"a":{"b/c":[1,2,3]},"0":"zero","d.e":{"f":1}}`
+	tests := []struct {
+		pointer string
+		expect  string
+	}{
+		{"/a/b~1c/1", `{"a":{"b/c":[1,3]},"0":"zero","d.e":{"f":1}}`},
+		{"/0", `{"a":{"b/c":[1,2,3]},"d.e":{"f":1}}`},
+		{"/d.e/f", `{"a":{"b/c":[1,2,3]},"0":"zero","d.e":{}}`},
+		{"/a/b~1c/-", json},
+		{"/a/b~1c/3", json},
+		{"/nothing/here", json},
+	}
+	for _, tt := range tests {
+		res, err := DeletePointer(json, tt.pointer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res != tt.expect {
+			t.Fatalf("pointer '%v': expected '%v', got '%v'", tt.pointer, tt.expect, res)
+		}
+	}
+	_, err := DeletePointer(json, "")
+	assert(t, err != nil)
+}
@@ -0,0 +1,422 @@
+package jj
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// queryCond is a condition of an extended '#(...)' query. Next to the
+// classic single comparison, like '#(age>30)', the extended query syntax
+// supports:
+//
+//	#(age>30 && city=="Paris")     and
+//	#(age<30 || age>60)            or
+//	#(!(age>30))                   not, and grouping with parentheses
+//	#(name=~"^D")                  regular expression match, '!~' for no match
+//	#(city in ["Paris","Rome"])    the value is in the list
+//	#(tags ∋ "x")                  the array contains the value, also 'contains'
+//	#(exists(nets))                the path exists
+//
+// The pattern of '=~' is a quoted string, as '#(active=~true)' is the
+// classic comparison with a value converted to a boolean. The 'in' and '∋'
+// operators compare values by their json type, so 1 does not equal "1".
+// For a string '∋' tests for a substring.
+type queryCond interface {
+	match(v Result) bool
+}
+
+// queryInvalid is the condition of a malformed extended query, which
+// matches nothing.
+type queryInvalid struct{}
+
+func (queryInvalid) match(Result) bool { return false }
+
+type queryOr struct{ left, right queryCond }
+
+func (q *queryOr) match(v Result) bool { return q.left.match(v) || q.right.match(v) }
+
+type queryAnd struct{ left, right queryCond }
+
+func (q *queryAnd) match(v Result) bool { return q.left.match(v) && q.right.match(v) }
+
+type queryNot struct{ cond queryCond }
+
+func (q *queryNot) match(v Result) bool { return !q.cond.match(v) }
+
+type queryExists struct{ path string }
+
+func (q *queryExists) match(v Result) bool { return queryValue(v, q.path).Exists() }
+
+type queryCmp struct {
+	path  string
+	op    string
+	value string         // the value of the classic operators
+	lit   Result         // the value of '∋'
+	list  []Result       // the values of 'in'
+	re    *regexp.Regexp // the pattern of '=~' and '!~'
+}
+
+func (q *queryCmp) match(v Result) bool {
+	res := queryValue(v, q.path)
+	if !res.Exists() {
+		return false
+	}
+	switch q.op {
+	case "=~", "!~":
+		return res.Type == String && q.re.MatchString(res.Str) == (q.op == "=~")
+	case "in":
+		for _, item := range q.list {
+			if jsonEqual(res, item) {
+				return true
+			}
+		}
+		return false
+	case "∋":
+		if res.Type == String && q.lit.Type == String {
+			return strings.Contains(res.Str, q.lit.Str)
+		}
+		var found bool
+		if res.IsArray() {
+			res.ForEach(func(_, value Result) bool {
+				found = jsonEqual(value, q.lit)
+				return !found
+			})
+		}
+		return found
+	}
+	return compareQuery(q.op, q.value, res)
+}
+
+// queryValue gets the path of a query from an array element.
+func queryValue(v Result, path string) Result {
+	if path == "" {
+		return v
+	}
+	if v.Type != JSON {
+		return Result{}
+	}
+	return v.Get(path)
+}
+
+// isExtendedQuery tells if the inside of a '#(...)' query uses any of the
+// extended query syntax.
+func isExtendedQuery(q string) bool {
+	var depth int
+	for i := 0; i < len(q); i++ {
+		switch q[i] {
+		case '\\':
+			i++
+		case '"':
+			i = skipQueryString(q, i)
+		case '(':
+			if depth == 0 && (i == 0 || q[i-1] == '!' || strings.HasSuffix(q[:i], "exists")) {
+				return true
+			}
+			depth++
+		case '[':
+			depth++
+		case ')', ']':
+			depth--
+		default:
+			if depth > 0 {
+				continue
+			}
+			if strings.HasPrefix(q[i:], "&&") || strings.HasPrefix(q[i:], "||") ||
+				strings.HasPrefix(q[i:], "∋") || isRegexpOp(q[i:]) {
+				return true
+			}
+			if (i == 0 || q[i] == ' ') && queryKeyword(q[i:]) != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isRegexpOp tells if s starts with a '=~' or '!~' followed by a string,
+// which tells it apart from the '~' of '#(active=~true)' that converts
+// the value to a boolean.
+func isRegexpOp(s string) bool {
+	if !strings.HasPrefix(s, "=~") && !strings.HasPrefix(s, "!~") {
+		return false
+	}
+	s = strings.TrimLeft(s[2:], " \t")
+	return len(s) > 0 && s[0] == '"'
+}
+
+// skipQueryString returns the position of the closing quote of the string
+// starting at i.
+func skipQueryString(q string, i int) int {
+	for i++; i < len(q); i++ {
+		if q[i] == '\\' {
+			i++
+		} else if q[i] == '"' {
+			break
+		}
+	}
+	return i
+}
+
+// queryKeyword returns the 'in' or 'contains' operator following the
+// whitespace at the start of s.
+func queryKeyword(s string) string {
+	s = strings.TrimLeft(s, " \t")
+	for _, kw := range []string{"in", "contains"} {
+		if strings.HasPrefix(s, kw) && len(s) > len(kw) {
+			switch s[len(kw)] {
+			case ' ', '\t', '[', '"':
+				return kw
+			}
+		}
+	}
+	return ""
+}
+
+var (
+	queryExprCache     sync.Map // map[string]queryCond
+	queryExprCacheSize atomic.Int64
+)
+
+// maxQueryExprCache is the number of queries that cachedQueryExpr keeps.
+const maxQueryExprCache = 1024
+
+// cachedQueryExpr returns the parsed condition of an extended query, which
+// is queryInvalid when the query is malformed. The conditions are cached,
+// so that a query and its regular expressions are parsed only once, and
+// not at every array that Get looks into.
+func cachedQueryExpr(q string) queryCond {
+	if cond, ok := queryExprCache.Load(q); ok {
+		return cond.(queryCond)
+	}
+	cond, ok := parseQueryExpr(q)
+	if !ok {
+		cond = queryInvalid{}
+	}
+	if queryExprCacheSize.Add(1) <= maxQueryExprCache {
+		queryExprCache.Store(q, cond)
+	}
+	return cond
+}
+
+type queryParser struct {
+	q string
+	i int
+}
+
+// parseQueryExpr parses the inside of an extended '#(...)' query.
+func parseQueryExpr(q string) (queryCond, bool) {
+	p := &queryParser{q: q}
+	cond, ok := p.or()
+	p.ws()
+	if !ok || p.i != len(p.q) {
+		return nil, false
+	}
+	return cond, true
+}
+
+func (p *queryParser) ws() {
+	for p.i < len(p.q) && (p.q[p.i] == ' ' || p.q[p.i] == '\t') {
+		p.i++
+	}
+}
+
+func (p *queryParser) eat(s string) bool {
+	if strings.HasPrefix(p.q[p.i:], s) {
+		p.i += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) or() (queryCond, bool) {
+	left, ok := p.and()
+	for ok {
+		p.ws()
+		if !p.eat("||") {
+			break
+		}
+		var right queryCond
+		right, ok = p.and()
+		left = &queryOr{left, right}
+	}
+	return left, ok
+}
+
+func (p *queryParser) and() (queryCond, bool) {
+	left, ok := p.unary()
+	for ok {
+		p.ws()
+		if !p.eat("&&") {
+			break
+		}
+		var right queryCond
+		right, ok = p.unary()
+		left = &queryAnd{left, right}
+	}
+	return left, ok
+}
+
+func (p *queryParser) unary() (queryCond, bool) {
+	p.ws()
+	if p.i >= len(p.q) {
+		return nil, false
+	}
+	switch {
+	case p.q[p.i] == '!' && (p.i+1 == len(p.q) || !strings.ContainsRune("=%~", rune(p.q[p.i+1]))):
+		p.i++
+		cond, ok := p.unary()
+		return &queryNot{cond}, ok
+	case p.q[p.i] == '(':
+		p.i++
+		cond, ok := p.or()
+		p.ws()
+		return cond, ok && p.eat(")")
+	case p.eat("exists("):
+		path := p.path()
+		p.ws()
+		return &queryExists{path: path}, p.eat(")")
+	}
+	return p.cmp()
+}
+
+// path reads a path up to an operator, or to the end of the condition.
+func (p *queryParser) path() string {
+	s := p.i
+	var depth int
+	for ; p.i < len(p.q); p.i++ {
+		c := p.q[p.i]
+		switch c {
+		case '\\':
+			p.i++
+			continue
+		case '"':
+			p.i = skipQueryString(p.q, p.i)
+			continue
+		case '(', '[':
+			depth++
+			continue
+		}
+		if depth > 0 {
+			if c == ')' || c == ']' {
+				depth--
+			}
+			continue
+		}
+		if strings.ContainsRune("!=<>%) \t", rune(c)) ||
+			strings.HasPrefix(p.q[p.i:], "&&") || strings.HasPrefix(p.q[p.i:], "||") ||
+			strings.HasPrefix(p.q[p.i:], "∋") {
+			break
+		}
+	}
+	if p.i > len(p.q) {
+		p.i = len(p.q)
+	}
+	return p.q[s:p.i]
+}
+
+func (p *queryParser) cmp() (queryCond, bool) {
+	q := &queryCmp{}
+	if queryKeyword(p.q[p.i:]) == "" {
+		// not an operator on the element itself, like #(in [1,2])
+		q.path = p.path()
+	}
+	if kw := queryKeyword(p.q[p.i:]); kw != "" {
+		p.ws()
+		p.i += len(kw)
+		q.op = kw
+	} else {
+		p.ws()
+		if isRegexpOp(p.q[p.i:]) {
+			q.op = p.q[p.i : p.i+2]
+			p.i += 2
+		} else {
+			for _, op := range []string{"==", "!=", "!%", "<=", ">=", "=", "<", ">", "%", "∋"} {
+				if p.eat(op) {
+					q.op = op
+					break
+				}
+			}
+		}
+	}
+	switch q.op {
+	case "":
+		// the existence of the path, like #(name)
+		return q, q.path != ""
+	case "==":
+		q.op = "="
+	case "contains":
+		q.op = "∋"
+	}
+	p.ws()
+	raw, str, ok := p.value()
+	if !ok {
+		return nil, false
+	}
+	switch q.op {
+	case "=~", "!~":
+		var err error
+		if q.re, err = regexp.Compile(str); err != nil {
+			return nil, false
+		}
+	case "in":
+		list := Parse(raw)
+		if !list.IsArray() {
+			return nil, false
+		}
+		q.list = list.Array()
+	case "∋":
+		if raw[0] == '"' {
+			q.lit = Result{Type: String, Raw: raw, Str: str}
+		} else {
+			q.lit = Parse(raw)
+		}
+	default:
+		q.value = str
+	}
+	return q, true
+}
+
+// value reads a json value, or an unquoted word like the value of
+// '#(last=Murphy)'. The str is the unquoted content of a string.
+func (p *queryParser) value() (raw, str string, ok bool) {
+	s := p.i
+	if p.i >= len(p.q) {
+		return "", "", false
+	}
+	switch p.q[p.i] {
+	case '"':
+		p.i = skipQueryString(p.q, p.i)
+		if p.i >= len(p.q) {
+			return "", "", false
+		}
+		p.i++
+		raw = p.q[s:p.i]
+		return raw, Parse(raw).Str, true
+	case '[', '{':
+		var depth int
+		for ; p.i < len(p.q); p.i++ {
+			switch p.q[p.i] {
+			case '"':
+				p.i = skipQueryString(p.q, p.i)
+			case '[', '{':
+				depth++
+			case ']', '}':
+				depth--
+			}
+			if depth == 0 {
+				p.i++
+				raw = p.q[s:p.i]
+				return raw, raw, true
+			}
+		}
+		return "", "", false
+	}
+	for p.i < len(p.q) && !strings.ContainsRune(") \t", rune(p.q[p.i])) &&
+		!strings.HasPrefix(p.q[p.i:], "&&") && !strings.HasPrefix(p.q[p.i:], "||") {
+		p.i++
+	}
+	raw = p.q[s:p.i]
+	return raw, raw, raw != ""
+}
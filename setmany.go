@@ -0,0 +1,242 @@
+package jj
+
+import (
+	"sort"
+	"unsafe"
+)
+
+// Update is a change of the SetMany function.
+type Update struct {
+	Path string
+	// Value is set like with Set, or as a raw block of json like with
+	// SetRaw when Raw is true.
+	Value interface{}
+	Raw   bool
+	// Delete deletes the value at the path, and Value is not used.
+	Delete bool
+}
+
+// SetMany applies the updates to the json in order, as if Set, SetRaw or
+// Delete were called for each one, and returns the paths of the updates
+// that created new objects or arrays.
+//
+// The updates of values that exist are applied together, in one rewrite of
+// the json, which is much faster than a Set per update on big documents.
+// The updates that add or delete values are applied one by one after them.
+// When the ReplaceInPlace and Optimistic options are set, and all the
+// updates replace values without making the json longer, the input json is
+// rewritten in place, and should not be used anymore. The IfAbsent and
+// IfPresent options are not used, and the MergeStruct option merges the
+// struct values that are not Raw.
+//
+//	json, created, err := jj.SetMany(json, []jj.Update{
+//		{Path: "name.first", Value: "Tom"},
+//		{Path: "tags", Value: `["a","b"]`, Raw: true},
+//		{Path: "address.city", Value: "Paris"},
+//		{Path: "age", Delete: true},
+//	})
+//	// created: [address.city], when there was no address
+func SetMany(json []byte, updates []Update, options ...SetOptions) ([]byte, []string, error) {
+	var opts SetOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	m := manySetter{json: *(*string)(unsafe.Pointer(&json))}
+	for _, u := range updates {
+		if u.Path == "" {
+			return json, nil, &SetError{Err: ErrInvalidPath, Reason: "path cannot be empty"}
+		}
+		sc := opts.config()
+		sc.del, sc.inplace = u.Delete, false
+		sc.ifAbsent, sc.ifPresent = false, false
+		var raw string
+		if !u.Delete {
+			var err error
+			if raw, err = valueRaw(u.Value, &sc); err != nil {
+				return json, nil, err
+			}
+			sc.stringify = sc.stringify && !u.Raw
+			sc.mergeStruct = opts.MergeStruct && !u.Raw && isStruct(u.Value)
+		}
+		m.add(u.Path, raw, sc)
+	}
+	return m.apply(json, opts.Optimistic && opts.ReplaceInPlace)
+}
+
+// setSpan is a span of the json that an update of SetMany changes.
+type setSpan struct {
+	start, end int
+}
+
+type setEdit struct {
+	setSpan
+	raw string
+}
+
+type laterSet struct {
+	path, raw string
+	sc        setConfig
+}
+
+type manySetter struct {
+	json  string
+	edits []setEdit  // the replacements of existing values
+	later []laterSet // the updates that are applied after the edits
+	// spans are the parts of the json changed by the updates so far, that
+	// the next edits must not overlap to be applied before the later ones.
+	spans []setSpan
+}
+
+func (m *manySetter) overlaps(span setSpan) bool {
+	for _, s := range m.spans {
+		if s.start < span.end && span.start < s.end {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *manySetter) add(path, raw string, sc setConfig) {
+	var res Result
+	optimistic := !sc.insert && isOptimisticPath(path, sc)
+	if optimistic {
+		res = Get(m.json, path, ApplyGetOption(sc.PathOption), DisableNegativeIndex(true))
+	}
+	span := setSpan{start: res.Index, end: res.Index + len(res.Raw)}
+	edit := optimistic && !sc.del && res.Exists() && res.Index > 0 && !m.overlaps(span)
+	if edit && sc.mergeStruct {
+		// an error is returned by the later set
+		merged, err := mergeStruct(res, raw)
+		if edit = err == nil; edit {
+			raw = merged
+		}
+	}
+	if edit {
+		if sc.stringify {
+			raw = string(appendStringify(nil, raw))
+		}
+		m.edits = append(m.edits, setEdit{setSpan: span, raw: raw})
+		m.spans = append(m.spans, span)
+		return
+	}
+	switch {
+	case !optimistic || res.Exists() && res.Index == 0:
+		span = setSpan{start: 0, end: len(m.json)}
+	case !res.Exists():
+		span = m.insertSpan(path, sc)
+		if sc.del && !m.overlaps(span) {
+			return // nothing to delete, and no update before creates it
+		}
+	case sc.del && !m.isMember(res.Index):
+		// deleting an array element moves the next ones
+		span.end = len(m.json)
+	}
+	m.later = append(m.later, laterSet{path: path, raw: raw, sc: sc})
+	m.spans = append(m.spans, span)
+}
+
+// isMember tells if the value at the index is the value of an object member.
+func (m *manySetter) isMember(index int) bool {
+	for i := index - 1; i >= 0; i-- {
+		if m.json[i] > ' ' {
+			return m.json[i] == ':'
+		}
+	}
+	return false
+}
+
+// insertSpan returns the span of the closing bracket of the container where
+// a value is added at the path, or of the value that the path replaces with
+// a new container.
+func (m *manySetter) insertSpan(path string, sc setConfig) setSpan {
+	paths, _ := parsePaths(path, sc)
+	for n := len(paths) - 1; n > 0; n-- {
+		gpath := paths[0].gpart
+		for _, p := range paths[1:n] {
+			gpath += "." + p.gpart
+		}
+		res := Get(m.json, gpath, ApplyGetOption(sc.PathOption), DisableNegativeIndex(true))
+		switch {
+		case !res.Exists():
+			continue
+		case res.Index == 0:
+			return setSpan{start: 0, end: len(m.json)}
+		case res.IsObject() || res.IsArray():
+			end := res.Index + len(res.Raw)
+			return setSpan{start: end - 1, end: end}
+		}
+		return setSpan{start: res.Index, end: res.Index + len(res.Raw)}
+	}
+	end := len(m.json)
+	for end > 0 && m.json[end-1] <= ' ' {
+		end--
+	}
+	if root := Parse(m.json); end > 0 && (root.IsObject() || root.IsArray()) {
+		return setSpan{start: end - 1, end: end}
+	}
+	return setSpan{start: 0, end: len(m.json)}
+}
+
+// createsContainer tells if setting the path creates new objects or arrays,
+// because its parent is missing.
+func createsContainer(jstr, path string, sc setConfig) bool {
+	paths, simple := parsePaths(path, sc)
+	if !simple {
+		return false
+	}
+	parent := Parse(jstr)
+	if len(paths) > 1 {
+		gpath := paths[0].gpart
+		for _, p := range paths[1 : len(paths)-1] {
+			gpath += "." + p.gpart
+		}
+		parent = Get(jstr, gpath, ApplyGetOption(sc.PathOption), DisableNegativeIndex(true))
+	}
+	return !parent.IsObject() && !parent.IsArray()
+}
+
+func (m *manySetter) apply(json []byte, inplace bool) ([]byte, []string, error) {
+	buf := json
+	if len(m.edits) > 0 {
+		sort.Slice(m.edits, func(i, j int) bool {
+			return m.edits[i].start < m.edits[j].start
+		})
+		// the json can be rewritten in place when it never grows, so that
+		// the bytes to copy are not overwritten before.
+		inplace = inplace && len(m.later) == 0
+		size := len(m.json)
+		for _, e := range m.edits {
+			size += len(e.raw) - (e.end - e.start)
+			inplace = inplace && size <= len(m.json)
+		}
+		if inplace {
+			buf = json[:0]
+		} else {
+			buf = make([]byte, 0, size)
+		}
+		prev := 0
+		for _, e := range m.edits {
+			buf = append(buf, m.json[prev:e.start]...)
+			buf = append(buf, e.raw...)
+			prev = e.end
+		}
+		buf = append(buf, m.json[prev:]...)
+	}
+	var created []string
+	for _, l := range m.later {
+		jstr := *(*string)(unsafe.Pointer(&buf))
+		creates := !l.sc.del && createsContainer(jstr, l.path, l.sc)
+		res, _, err := set(jstr, l.path, l.raw, l.sc)
+		if err == errNoChange {
+			continue
+		}
+		if err != nil {
+			return json, nil, err
+		}
+		if creates {
+			created = append(created, l.path)
+		}
+		buf = res
+	}
+	return buf, created, nil
+}
@@ -0,0 +1,76 @@
+package jj
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetMany(t *testing.T) {
+	json := `{"name":{"first":"Tom","last":"Anderson"},"age":37,
+		"children":["Sara","Alex","Jack"],"friends":[{"first":"James"},{"first":"Roger"}]}`
+	tests := [][]Update{
+		{{Path: "age", Value: 38}, {Path: "name.first", Value: "Sam"}, {Path: "children.1", Value: "Bob"}},
+		{{Path: "name.last", Value: "Smith"}, {Path: "name.first", Value: `"quoted"`}},
+		{{Path: "address.city", Value: "Paris"}, {Path: "age", Value: 1}, {Path: "address.zip", Value: "75001"}},
+		{{Path: "name", Value: `{"x":1}`, Raw: true}, {Path: "name.first", Value: "Tom"}},
+		{{Path: "name.first", Value: "Tom"}, {Path: "name", Value: `[1]`, Raw: true}, {Path: "name.1", Value: 2}},
+		{{Path: "children.0", Delete: true}, {Path: "children.0", Value: "Ann"}, {Path: "age", Value: 2}},
+		{{Path: "name.first", Delete: true}, {Path: "name.last", Value: nil}, {Path: "missing", Delete: true}},
+		{{Path: "children.-1", Value: "Zoe"}, {Path: "children.2", Value: "Max"}, {Path: "friends.1.first", Value: "Rob"}},
+		{{Path: "friends.#.first", Value: "All"}, {Path: "friends.0.first", Value: "One"}},
+		{{Path: "age.years", Value: 37}, {Path: "age.years", Value: 38}, {Path: "x.y.z", Value: true}},
+		{{Path: "friends.0.first", Value: "A"}, {Path: "friends.0.last", Value: "B"}, {Path: "friends", Delete: true}},
+		{{Path: "age.1", Delete: true}, {Path: "age", Value: `[1,{"x":2}]`, Raw: true}},
+		{{Path: "age", Value: `{"x":2}`, Raw: true}, {Path: "age.x", Delete: true}},
+	}
+	for _, updates := range tests {
+		expect := json
+		for _, u := range updates {
+			var err error
+			switch {
+			case u.Delete:
+				expect, err = Delete(expect, u.Path)
+			case u.Raw:
+				expect, err = SetRaw(expect, u.Path, u.Value.(string))
+			default:
+				expect, err = Set(expect, u.Path, u.Value)
+			}
+			assert(t, err == nil)
+		}
+		got, _, err := SetMany([]byte(json), updates)
+		if err != nil || string(got) != expect {
+			t.Fatalf("updates '%v': expected '%v', got '%v' %v", updates, expect, string(got), err)
+		}
+	}
+}
+
+func TestSetManyCreated(t *testing.T) {
+	got, created, err := SetMany([]byte(`{"a":{"b":1}}`), []Update{
+		{Path: "a.b", Value: 2},
+		{Path: "a.c", Value: 3},
+		{Path: "d.e.f", Value: 4},
+		{Path: "d.e.g", Value: 5},
+		{Path: "h.0", Value: 6},
+	})
+	assert(t, err == nil && string(got) == `{"a":{"b":2,"c":3},"d":{"e":{"f":4,"g":5}},"h":[6]}`)
+	assert(t, strings.Join(created, ",") == "d.e.f,h.0")
+
+	_, created, _ = SetMany(nil, []Update{{Path: "a", Value: 1}})
+	assert(t, len(created) == 1)
+
+	_, _, err = SetMany([]byte(`{}`), []Update{{Path: "a", Value: 1}, {Path: ""}})
+	assert(t, err != nil)
+}
+
+func TestSetManyInPlace(t *testing.T) {
+	json := []byte(`{"a":"hello","b":[1,2,3],"c":true}`)
+	opts := SetOptions{Optimistic: true, ReplaceInPlace: true}
+	got, _, err := SetMany(json, []Update{{Path: "c", Value: false}, {Path: "a", Value: "hi"}}, opts)
+	assert(t, err == nil && string(got) == `{"a":"hi","b":[1,2,3],"c":false}`)
+	assert(t, &got[0] == &json[0])
+
+	json = []byte(`{"a":"hello","b":[1,2,3],"c":true}`)
+	got, _, err = SetMany(json, []Update{{Path: "a", Value: "hello world"}, {Path: "b", Value: 1}}, opts)
+	assert(t, err == nil && string(got) == `{"a":"hello world","b":1,"c":true}`)
+	assert(t, &got[0] != &json[0] && string(json) == `{"a":"hello","b":[1,2,3],"c":true}`)
+}
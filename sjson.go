@@ -1,9 +1,13 @@
 package jj
 
 import (
+	"bytes"
 	jsongo "encoding/json"
+	"errors"
+	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"unsafe"
 )
 
@@ -15,6 +19,26 @@ func (err *errorType) Error() string {
 	return err.msg
 }
 
+// SetError is the error of the Set and Delete functions. It wraps one of
+// ErrInvalidPath, ErrPathNotFound and ErrConflict, or ErrInvalidJSON for
+// the invalid output of a Marshaler, which can be checked with errors.Is.
+type SetError struct {
+	Err    error
+	Path   string
+	Reason string
+}
+
+func (e *SetError) Error() string {
+	if e.Reason != "" {
+		return e.Reason
+	}
+	return e.Err.Error() + " " + strconv.Quote(e.Path)
+}
+
+func (e *SetError) Unwrap() error {
+	return e.Err
+}
+
 // SetOptions represents additional options for the Set and Delete functions.
 type SetOptions struct {
 	// Optimistic is a hint that the value likely exists which
@@ -29,10 +53,40 @@ type SetOptions struct {
 	// The Optimistic flag must be set to true and the input must be a
 	// byte slice in order to use this field.
 	ReplaceInPlace bool
+	// Marshaler marshals the values that are not strings, numbers or
+	// booleans, in place of encoding/json, like the Marshal of jsoniter.
+	Marshaler func(v interface{}) ([]byte, error)
+	// EscapeHTML escapes the <, > and & characters in the marshaled values,
+	// which are kept as is by default.
+	EscapeHTML bool
+	// Insert inserts the value into the array before the element at the
+	// index of the last path component, instead of replacing it, like the
+	// "[+1]" path component does.
+	Insert bool
+	// IfAbsent only sets a value that does not exist yet, and fails with
+	// ErrConflict otherwise. A path of many values skips the ones that
+	// exist, and fails only when none is set.
+	IfAbsent bool
+	// IfPresent only sets or deletes a value that exists, and fails with
+	// ErrPathNotFound otherwise. A path of many values skips the ones that
+	// do not exist, and fails only when none is set.
+	IfPresent bool
+	// MergeStruct merges a struct value into the object at the path like
+	// DeepMerge, field by field, instead of replacing the whole object.
+	MergeStruct bool
 
 	PathOption
 }
 
+// config returns the set config of the options.
+func (o SetOptions) config() setConfig {
+	sc := makeSetConfig(false, false, o.Optimistic, o.ReplaceInPlace)
+	sc.marshaler, sc.escapeHTML, sc.insert = o.Marshaler, o.EscapeHTML, o.Insert
+	sc.ifAbsent, sc.ifPresent = o.IfAbsent, o.IfPresent
+	sc.PathOption = o.PathOption
+	return sc
+}
+
 type pathResult struct {
 	part  string // current key part
 	gpart string // gjson get part
@@ -62,6 +116,9 @@ func parsePath(path string, sc setConfig) (res pathResult, simple bool) {
 		r.force = true
 		path = path[1:]
 	}
+	if isSlice(path) {
+		return r, false
+	}
 	for i := 0; i < len(path); i++ {
 		if path[i] == '.' {
 			r.part = path[:i]
@@ -116,6 +173,24 @@ func parsePath(path string, sc setConfig) (res pathResult, simple bool) {
 	return r, true
 }
 
+// parsePaths parses all the components of a path, and returns false when
+// the path is complex.
+func parsePaths(path string, sc setConfig) ([]pathResult, bool) {
+	var paths []pathResult
+	r, simple := parsePath(path, sc)
+	if !simple {
+		return nil, false
+	}
+	paths = append(paths, r)
+	for r.more {
+		if r, simple = parsePath(r.path, sc); !simple {
+			return nil, false
+		}
+		paths = append(paths, r)
+	}
+	return paths, true
+}
+
 func mustMarshalString(s string) bool {
 	for i := 0; i < len(s); i++ {
 		if s[i] < ' ' || s[i] > 0x7f || s[i] == '"' || s[i] == '\\' {
@@ -167,11 +242,81 @@ func appendBuild(buf []byte, array bool, paths []pathResult, raw string,
 	return buf
 }
 
+// layout is the format of the members of a multi-line object or array, that
+// new members follow.
+type layout struct {
+	next    string // the separator before a member, like ",\n  "
+	sep     string // the separator of the keys and the values, like ": "
+	end     int    // the end of the last member in the raw json
+	newline string
+	indent  string // the indent of the members
+	unit    string // the indent of a level, when it is known
+}
+
+// multilineLayout returns the layout of an object or array whose last member
+// is on a line of its own.
+func multilineLayout(c Result) (l layout, ok bool) {
+	raw := c.Raw
+	last := -1
+	c.ForEach(func(key, value Result) bool {
+		last = value.Index - c.Index
+		if key.Type == String {
+			last = key.Index - c.Index
+			l.sep = raw[last+len(key.Raw) : value.Index-c.Index]
+		}
+		l.end = value.Index - c.Index + len(value.Raw)
+		return true
+	})
+	if last < 0 || l.end > len(raw) {
+		return l, false
+	}
+	i := last
+	for i > 0 && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+		i--
+	}
+	if i == 0 || raw[i-1] != '\n' {
+		return l, false
+	}
+	l.newline, l.indent = "\n", raw[i:last]
+	if i > 1 && raw[i-2] == '\r' {
+		l.newline = "\r\n"
+	}
+	l.next = "," + l.newline + l.indent
+	// the indent of the closing bracket gives the one of a level
+	j := strings.LastIndexAny(raw, "}]")
+	k := j
+	for k > l.end && (raw[k-1] == ' ' || raw[k-1] == '\t') {
+		k--
+	}
+	if k > l.end && raw[k-1] == '\n' && strings.HasPrefix(l.indent, raw[k:j]) {
+		l.unit = l.indent[j-k:]
+	}
+	return l, true
+}
+
+// appendBuild appends the json block built from a path, which is formatted
+// in the layout when it makes new objects or arrays.
+func (l layout) appendBuild(buf []byte, paths []pathResult, raw string, stringify bool) []byte {
+	if len(paths) == 1 || l.unit == "" {
+		return appendBuild(buf, true, paths, raw, stringify)
+	}
+	opts := Options{Width: 80, Prefix: l.indent, Indent: l.unit}
+	block := Pretty(appendBuild(nil, true, paths, raw, stringify), opts)
+	block = bytes.TrimSuffix(block, []byte{'\n'})
+	if l.newline != "\n" {
+		block = bytes.ReplaceAll(block, []byte{'\n'}, []byte(l.newline))
+	}
+	return append(buf, block...)
+}
+
 // atoui does a rip conversion of string -> unigned int.
 func atoui(r pathResult) (n int, ok bool) {
 	if r.force {
 		return 0, false
 	}
+	if n, ok := insertIndex(r); ok {
+		return n, true
+	}
 	for i := 0; i < len(r.part); i++ {
 		if r.part[i] < '0' || r.part[i] > '9' {
 			return 0, false
@@ -181,6 +326,87 @@ func atoui(r pathResult) (n int, ok bool) {
 	return n, true
 }
 
+// insertIndex returns the index of an insert path component, like '[+1]'.
+func insertIndex(r pathResult) (n int, ok bool) {
+	if r.force || len(r.part) < 4 || r.part[:2] != "[+" || r.part[len(r.part)-1] != ']' {
+		return 0, false
+	}
+	return atoui(pathResult{part: r.part[2 : len(r.part)-1]})
+}
+
+// checkInserts checks that the '[+N]' components of the paths insert into
+// arrays, before an element or right after the last one. A missing array is
+// created, so N must be 0 then.
+func checkInserts(jstr string, paths []pathResult, sc setConfig) *SetError {
+	gpath := ""
+	for k, p := range paths {
+		if k > 0 {
+			gpath += "."
+		}
+		gpath += p.gpart
+		n, ok := insertIndex(p)
+		if !ok {
+			continue
+		}
+		parent := Parse(jstr)
+		if k > 0 {
+			parent = Get(jstr, gpath[:len(gpath)-len(p.gpart)-1], ApplyGetOption(sc.PathOption), DisableNegativeIndex(true))
+		}
+		var count int
+		switch {
+		case parent.IsArray():
+			parent.ForEach(func(_, _ Result) bool {
+				count++
+				return true
+			})
+		case parent.Exists() && strings.TrimSpace(parent.Raw) != "":
+			return &SetError{Err: ErrInvalidPath, Reason: "cannot insert " + p.part + " into a value that is not an array"}
+		}
+		if n > count {
+			return &SetError{Err: ErrInvalidPath, Reason: "cannot insert " + p.part + " into an array of " + strconv.Itoa(count) + " elements"}
+		}
+	}
+	return nil
+}
+
+// appendInserted appends the json to buf with the value inserted into the
+// array before the element at the index, and returns false when the json is
+// not an array with such an element.
+func appendInserted(buf []byte, jstr string, n int, raw string, stringify bool) ([]byte, bool) {
+	i := 0
+	for i < len(jstr) && jstr[i] <= ' ' {
+		i++
+	}
+	arr := Parse(jstr[i:])
+	if !arr.IsArray() {
+		return buf, false
+	}
+	index := -1
+	arr.ForEach(func(_, value Result) bool {
+		if n == 0 {
+			index = i + value.Index
+			return false
+		}
+		n--
+		return true
+	})
+	if index < 0 {
+		return buf, false
+	}
+	next := ","
+	if l, ok := multilineLayout(arr); ok {
+		next = l.next
+	}
+	buf = append(buf, jstr[:index]...)
+	if stringify {
+		buf = appendStringify(buf, raw)
+	} else {
+		buf = append(buf, raw...)
+	}
+	buf = append(buf, next...)
+	return append(buf, jstr[index:]...), true
+}
+
 // appendRepeat repeats string "n" times and appends to buf.
 func appendRepeat(buf []byte, s string, n int) []byte {
 	for i := 0; i < n; i++ {
@@ -196,7 +422,7 @@ loop:
 		// look for either a ',',':','['
 		switch buf[i] {
 		case '[':
-			return buf, true
+			return buf[:i+1], true
 		case ',':
 			return buf[:i], false
 		case ':':
@@ -232,12 +458,56 @@ loop:
 	return buf, false
 }
 
+// appendDeleted appends the json to buf, leaving out the value of res along
+// with its key and the comma that separates it from the other values.
+func appendDeleted(buf []byte, jstr string, res Result) []byte {
+	buf = append(buf, jstr[:res.Index]...)
+	var exidx int // additional forward stripping
+	var delNextComma bool
+	buf, delNextComma = deleteTailItem(buf)
+	if delNextComma {
+		i, j := res.Index+len(res.Raw), 0
+		for ; i < len(jstr); i, j = i+1, j+1 {
+			if jstr[i] <= ' ' {
+				continue
+			}
+			if jstr[i] == ',' {
+				exidx = j + 1
+			} else if jstr[i] == '}' || jstr[i] == ']' {
+				exidx = j // the container is empty now
+			}
+			break
+		}
+	}
+	return append(buf, jstr[res.Index+len(res.Raw)+exidx:]...)
+}
+
 var errNoChange = &errorType{"no change"}
 
 func appendRawPaths(buf []byte, jstr string, paths []pathResult, raw string, sc setConfig) ([]byte, error) {
 	var err error
 	var res Result
 	var found bool
+	if !sc.del {
+		n, ok := insertIndex(paths[0])
+		if !ok && sc.insert && len(paths) == 1 && paths[0].part != "" {
+			n, ok = atoui(paths[0])
+		}
+		if ok {
+			elem, stringify := raw, sc.stringify
+			if len(paths) > 1 {
+				// the inserted element is a new value with the rest of the path
+				b, err := appendRawPaths(nil, "", paths[1:], raw, sc)
+				if err != nil {
+					return nil, err
+				}
+				elem, stringify = string(b), false
+			}
+			if ibuf, inserted := appendInserted(buf, jstr, n, elem, stringify); inserted {
+				return ibuf, nil
+			}
+		}
+	}
 	if sc.del {
 		if sc.RawPath {
 			res = Get(jstr, paths[0].gpart, ApplyGetOption(sc.PathOption))
@@ -262,31 +532,16 @@ func appendRawPaths(buf []byte, jstr string, paths []pathResult, raw string, sc
 			buf = append(buf, jstr[res.Index+len(res.Raw):]...)
 			return buf, nil
 		}
-		buf = append(buf, jstr[:res.Index]...)
-		var exidx int // additional forward stripping
 		if sc.del {
-			var delNextComma bool
-			buf, delNextComma = deleteTailItem(buf)
-			if delNextComma {
-				i, j := res.Index+len(res.Raw), 0
-				for ; i < len(jstr); i, j = i+1, j+1 {
-					if jstr[i] <= ' ' {
-						continue
-					}
-					if jstr[i] == ',' {
-						exidx = j + 1
-					}
-					break
-				}
-			}
+			return appendDeleted(buf, jstr, res), nil
+		}
+		buf = append(buf, jstr[:res.Index]...)
+		if sc.stringify {
+			buf = appendStringify(buf, raw)
 		} else {
-			if sc.stringify {
-				buf = appendStringify(buf, raw)
-			} else {
-				buf = append(buf, raw...)
-			}
+			buf = append(buf, raw...)
 		}
-		buf = append(buf, jstr[res.Index+len(res.Raw)+exidx:]...)
+		buf = append(buf, jstr[res.Index+len(res.Raw):]...)
 		return buf, nil
 	}
 	if sc.del {
@@ -329,7 +584,7 @@ func appendRawPaths(buf []byte, jstr string, paths []pathResult, raw string, sc
 	}
 	switch jsres.Raw[0] {
 	default:
-		return nil, &errorType{"json must be an object or array"}
+		return nil, &SetError{Err: ErrInvalidPath, Reason: "json must be an object or array"}
 	case '{':
 		end := len(jsres.Raw) - 1
 		for ; end > 0; end-- {
@@ -337,6 +592,14 @@ func appendRawPaths(buf []byte, jstr string, paths []pathResult, raw string, sc
 				break
 			}
 		}
+		if l, ok := multilineLayout(jsres); ok {
+			buf = append(buf, jsres.Raw[:l.end]...)
+			buf = append(buf, l.next...)
+			buf = appendStringify(buf, paths[0].part)
+			buf = append(buf, l.sep...)
+			buf = l.appendBuild(buf, paths, raw, sc.stringify)
+			return append(buf, jsres.Raw[l.end:]...), nil
+		}
 		buf = append(buf, jsres.Raw[:end]...)
 		if comma {
 			buf = append(buf, ',')
@@ -350,12 +613,24 @@ func appendRawPaths(buf []byte, jstr string, paths []pathResult, raw string, sc
 			if paths[0].part == "-1" && !paths[0].force {
 				appendit = true
 			} else {
-				return nil, &errorType{
-					"cannot set array element for non-numeric key '" +
-						paths[0].part + "'",
+				return nil, &SetError{
+					Err:    ErrInvalidPath,
+					Reason: "cannot set array element for non-numeric key '" + paths[0].part + "'",
 				}
 			}
 		}
+		if l, ok := multilineLayout(jsres); ok {
+			buf = append(buf, jsres.Raw[:l.end]...)
+			if !appendit {
+				for i := len(jsres.Array()); i < n; i++ {
+					buf = append(buf, l.next...)
+					buf = append(buf, "null"...)
+				}
+			}
+			buf = append(buf, l.next...)
+			buf = l.appendBuild(buf, paths, raw, sc.stringify)
+			return append(buf, jsres.Raw[l.end:]...), nil
+		}
 		if appendit {
 			njson := trim(jsres.Raw)
 			if njson[len(njson)-1] == ']' {
@@ -415,17 +690,58 @@ func isOptimisticPath(path string, sc setConfig) bool {
 // This function works the same as Set except that the value is set as a
 // raw block of json. This allows for setting premarshalled json objects.
 func SetRaw(json, path, value string, options ...SetOptions) (string, error) {
-	var optimistic bool
+	var opts SetOptions
 	if len(options) > 0 {
-		optimistic = options[0].Optimistic
+		opts = options[0]
 	}
-	res, err := set(json, path, value, makeSetConfig(false, false, optimistic, false))
+	sc := opts.config()
+	sc.inplace = false
+	res, _, err := set(json, path, value, sc)
 	if err == errNoChange {
 		return json, nil
 	}
 	return string(res), err
 }
 
+// SetIf sets a json value for the specified path like Set, only when the
+// current value is equal to the expected raw json, which is a compare and
+// swap. It fails with ErrPathNotFound when there is no value, and with
+// ErrConflict when the value is another one. The order of the keys and the
+// formats of the numbers do not matter for equality. A path of many values,
+// with a wildcard or a '#', and a path that cannot be set, like one with a
+// pipe, are ErrInvalidPath errors.
+//
+//	json, err := jj.SetIf(json, "version", "3", 4)
+//	if errors.Is(err, jj.ErrConflict) {
+//		// reload the json and retry
+//	}
+func SetIf(json, path, expected string, value interface{}, options ...SetOptions) (string, error) {
+	var opts SetOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	sc := opts.config()
+	if _, simple := parsePaths(path, sc); !simple {
+		if isMultiPath(path) {
+			return json, &SetError{Err: ErrInvalidPath, Path: path, Reason: "cannot compare and swap the values of " + strconv.Quote(path)}
+		}
+		// the paths that cannot be set, like the ones with pipes, fail
+		// before they are compared
+		if _, _, err := complexValues(json, path, sc); err != nil {
+			return json, err
+		}
+	}
+	cur := Get(json, path, ApplyGetOption(opts.PathOption), DisableNegativeIndex(true))
+	if !cur.Exists() {
+		return json, &SetError{Err: ErrPathNotFound, Path: path}
+	}
+	d := differ{opts: DiffOptions{IgnoreKeyOrder: true, NumericNumbers: true}}
+	if !d.equal(cur, Parse(expected)) {
+		return json, &SetError{Err: ErrConflict, Path: path, Reason: "value at " + strconv.Quote(path) + " is not the expected one"}
+	}
+	return Set(json, path, value, options...)
+}
+
 type dtype struct{}
 
 // Delete deletes a value from json for the specified path.
@@ -433,6 +749,12 @@ func Delete(json, path string, options ...SetOptions) (string, error) {
 	return Set(json, path, dtype{}, options...)
 }
 
+// DeleteAll deletes every value that the path matches, like SetAll, and
+// returns the number of deleted values.
+func DeleteAll(json, path string, options ...SetOptions) (string, int, error) {
+	return SetAll(json, path, dtype{}, options...)
+}
+
 // DeleteBytes deletes a value from json for the specified path.
 func DeleteBytes(json []byte, path string, options ...SetOptions) ([]byte, error) {
 	return SetBytes(json, path, dtype{}, options...)
@@ -440,6 +762,9 @@ func DeleteBytes(json []byte, path string, options ...SetOptions) ([]byte, error
 
 type setConfig struct {
 	stringify, del, optimistic, inplace bool
+	escapeHTML, insert, mergeStruct     bool
+	ifAbsent, ifPresent                 bool
+	marshaler                           func(v interface{}) ([]byte, error)
 	PathOption
 }
 
@@ -447,11 +772,35 @@ func makeSetConfig(stringify, del, optimistic, inplace bool) setConfig {
 	return setConfig{stringify: stringify, del: del, optimistic: optimistic, inplace: inplace}
 }
 
-func set(jstr, path, raw string, sc setConfig) ([]byte, error) {
+// set sets the raw value at the path, and returns the number of values that
+// are changed, which can be more than one for a complex path.
+func set(jstr, path, raw string, sc setConfig) ([]byte, int, error) {
 	if path == "" {
-		return nil, &errorType{"path cannot be empty"}
+		return nil, 0, &SetError{Err: ErrInvalidPath, Reason: "path cannot be empty"}
+	}
+	paths, simple := parsePaths(path, sc)
+	if simple && !sc.del {
+		if err := checkInserts(jstr, paths, sc); err != nil {
+			err.Path = path
+			return []byte(jstr), 0, err
+		}
+	}
+	if simple && sc.mergeStruct {
+		var err error
+		if raw, err = mergeStruct(Get(jstr, path, ApplyGetOption(sc.PathOption)), raw); err != nil {
+			return []byte(jstr), 0, err
+		}
+	}
+	if simple && (sc.ifAbsent || sc.ifPresent) {
+		exists := Get(jstr, path, ApplyGetOption(sc.PathOption), DisableNegativeIndex(true)).Exists()
+		if sc.ifAbsent && exists {
+			return []byte(jstr), 0, &SetError{Err: ErrConflict, Path: path, Reason: "value already exists at " + strconv.Quote(path)}
+		}
+		if sc.ifPresent && !exists {
+			return []byte(jstr), 0, &SetError{Err: ErrPathNotFound, Path: path}
+		}
 	}
-	if !sc.del && sc.optimistic && isOptimisticPath(path, sc) {
+	if !sc.del && !sc.insert && sc.optimistic && isOptimisticPath(path, sc) {
 		res := Get(jstr, path, ApplyGetOption(sc.PathOption), DisableNegativeIndex(true))
 		if res.Exists() && res.Index > 0 {
 			sz := len(jstr) - len(res.Raw) + len(raw)
@@ -474,9 +823,9 @@ func set(jstr, path, raw string, sc setConfig) ([]byte, error) {
 						copy(jbytes[res.Index+len(raw):],
 							jbytes[res.Index+len(res.Raw):])
 					}
-					return jbytes[:sz], nil
+					return jbytes[:sz], 1, nil
 				}
-				return []byte(jstr), nil
+				return []byte(jstr), 1, nil
 			}
 			buf := make([]byte, 0, sz)
 			buf = append(buf, jstr[:res.Index]...)
@@ -486,83 +835,276 @@ func set(jstr, path, raw string, sc setConfig) ([]byte, error) {
 				buf = append(buf, raw...)
 			}
 			buf = append(buf, jstr[res.Index+len(res.Raw):]...)
-			return buf, nil
-		}
-	}
-	var paths []pathResult
-	r, simple := parsePath(path, sc)
-	if simple {
-		paths = append(paths, r)
-		for r.more {
-			r, simple = parsePath(r.path, sc)
-			if !simple {
-				break
-			}
-			paths = append(paths, r)
+			return buf, 1, nil
 		}
 	}
 	if !simple {
-		if sc.del {
-			return []byte(jstr),
-				&errorType{"cannot delete value from a complex path"}
-		}
-		return setComplexPath(jstr, path, raw, sc.stringify)
+		return setComplexPath(jstr, path, raw, sc)
 	}
 	njson, err := appendRawPaths(nil, jstr, paths, raw, sc)
 	if err != nil {
-		return []byte(jstr), err
+		if se, ok := err.(*SetError); ok {
+			se.Path = path
+		}
+		return []byte(jstr), 0, err
 	}
-	return njson, nil
+	return njson, 1, nil
 }
 
-func setComplexPath(jstr, path, raw string, stringify bool) ([]byte, error) {
-	res := Get(jstr, path)
-	if !res.Exists() || !(res.Index != 0 || len(res.Indexes) != 0) {
-		return []byte(jstr), errNoChange
+// setComplexPath sets or deletes every value that a complex path matches,
+// like "friends.#(age>40)#.active" or "items.*.price".
+func setComplexPath(jstr, path, raw string, sc setConfig) ([]byte, int, error) {
+	vals, last, err := complexValues(jstr, path, sc)
+	if err != nil {
+		return []byte(jstr), 0, err
 	}
-	if res.Index != 0 {
-		njson := []byte(jstr[:res.Index])
-		if stringify {
-			njson = appendStringify(njson, raw)
-		} else {
-			njson = append(njson, raw...)
+	sort.Slice(vals, func(i, j int) bool {
+		return vals[i].Index > vals[j].Index
+	})
+	orig := jstr
+	var n int
+	for _, vres := range vals {
+		var njson []byte
+		switch {
+		case last != "":
+			// set the rest of the path in each parent, like a simple path
+			psc := sc
+			psc.inplace = false
+			parent, _, err := set(vres.Raw, last, raw, psc)
+			if err == errNoChange || errors.Is(err, ErrConflict) || errors.Is(err, ErrPathNotFound) {
+				continue
+			} else if err != nil {
+				if se, ok := err.(*SetError); ok {
+					se.Path = path
+				}
+				return []byte(orig), 0, err
+			}
+			njson = append([]byte(jstr[:vres.Index]), parent...)
+		case sc.ifAbsent:
+			continue
+		case sc.del:
+			jstr = string(appendDeleted(nil, jstr, vres))
+			n++
+			continue
+		case sc.mergeStruct:
+			// each match is merged with the struct on its own
+			merged, err := mergeStruct(vres, raw)
+			if err != nil {
+				return []byte(orig), 0, err
+			}
+			njson = append([]byte(jstr[:vres.Index]), merged...)
+		case sc.stringify:
+			njson = appendStringify([]byte(jstr[:vres.Index]), raw)
+		default:
+			njson = append([]byte(jstr[:vres.Index]), raw...)
 		}
-		njson = append(njson, jstr[res.Index+len(res.Raw):]...)
+		njson = append(njson, jstr[vres.Index+len(vres.Raw):]...)
 		jstr = string(njson)
+		n++
+	}
+	switch {
+	case n == 0 && len(vals) > 0 && sc.ifAbsent:
+		return []byte(orig), 0, &SetError{Err: ErrConflict, Path: path, Reason: "values already exist at " + strconv.Quote(path)}
+	case n == 0 && sc.ifPresent:
+		return []byte(orig), 0, &SetError{Err: ErrPathNotFound, Path: path}
+	case n == 0:
+		return []byte(jstr), 0, errNoChange
+	}
+	return []byte(jstr), n, nil
+}
+
+// isMultiPath tells if a path has wildcard keys, a '#' for all the elements
+// of an array or a '#(...)#' query, which can match many values.
+func isMultiPath(path string) bool {
+	cuts, err := scanPath(path)
+	if err != nil {
+		return false
 	}
-	if len(res.Indexes) > 0 {
-		type val struct {
-			index int
-			res   Result
+	start := 0
+	for n := 0; n <= len(cuts); n++ {
+		end := len(path)
+		if n < len(cuts) {
+			end = cuts[n]
 		}
-		vals := make([]val, 0, len(res.Indexes))
-		res.ForEach(func(_, vres Result) bool {
-			vals = append(vals, val{res: vres})
+		comp := path[start:end]
+		if comp == "#" || isWildcard(comp) || strings.HasPrefix(comp, "#(") && strings.HasSuffix(comp, ")#") {
 			return true
-		})
-		if len(res.Indexes) != len(vals) {
-			return []byte(jstr), errNoChange
 		}
-		for i := 0; i < len(res.Indexes); i++ {
-			vals[i].index = res.Indexes[i]
+		start = end + 1
+	}
+	return false
+}
+
+// complexValues returns the values that a complex path matches in the json.
+// The wildcard keys, the '#' of all the elements of an array and the '#(...)#'
+// queries match many values, component after component. When the rest of
+// the path is made of simple keys, the values are the parents of the rest,
+// which may not have it yet. The other paths are resolved by Get, and the
+// paths with pipes, modifiers or '**', and the ones whose values are not in
+// the json, like counts, are invalid.
+func complexValues(jstr, path string, sc setConfig) (vals []Result, rest string, err error) {
+	option := sc.PathOption
+	cuts, serr := scanPath(path)
+	if serr == nil && !DisableModifiers {
+		start := 0
+		for n := 0; n <= len(cuts); n++ {
+			end := len(path)
+			if n < len(cuts) {
+				end = cuts[n]
+			}
+			if end > start && path[start] == '@' && isModifier(path[start:end], &option) {
+				return nil, "", &SetError{Err: ErrInvalidPath, Path: path, Reason: "cannot set a path with a modifier"}
+			}
+			start = end + 1
 		}
-		sort.SliceStable(vals, func(i, j int) bool {
-			return vals[i].index > vals[j].index
-		})
+	}
+	if serr != nil || strings.HasPrefix(path, "..") {
+		vals, err = getValues(jstr, path, &option)
+		return vals, "", err
+	}
+	i := 0
+	for i < len(jstr) && jstr[i] <= ' ' {
+		i++
+	}
+	root := Parse(jstr[i:])
+	root.Index = i
+	vals = []Result{root}
+	start := 0
+	for n := 0; n <= len(cuts); n++ {
+		end := len(path)
+		if n < len(cuts) {
+			end = cuts[n]
+			if path[end] == '|' {
+				return nil, "", &SetError{Err: ErrInvalidPath, Path: path, Reason: "cannot set a path with a pipe"}
+			}
+		}
+		comp := path[start:end]
+		if comp == "**" {
+			return nil, "", &SetError{Err: ErrInvalidPath, Path: path, Reason: "cannot set a path with '**'"}
+		}
+		if n > 0 && strings.IndexByte(path[start:], '|') < 0 {
+			if _, simple := parsePaths(path[start:], sc); simple {
+				parents := vals[:0]
+				for _, val := range vals {
+					if val.IsObject() || val.IsArray() {
+						parents = append(parents, val)
+					}
+				}
+				return parents, path[start:], nil
+			}
+		}
+		start = end + 1
+		_, insert := insertIndex(pathResult{part: comp})
+		if comp == "" || !insert && strings.IndexByte("@[{!", comp[0]) >= 0 || comp == "#" && n == len(cuts) {
+			vals, err = getValues(jstr, path, &option)
+			return vals, "", err
+		}
+		var next []Result
 		for _, val := range vals {
-			vres := val.res
-			index := val.index
-			njson := []byte(jstr[:index])
-			if stringify {
-				njson = appendStringify(njson, raw)
-			} else {
-				njson = append(njson, raw...)
+			next = appendMatches(next, val, comp, &option)
+		}
+		vals = next
+	}
+	return vals, "", nil
+}
+
+// isModifier tells if a component of a path is a modifier that exists, as
+// Get reads an unknown one like "@context" as a key.
+func isModifier(comp string, option *PathOption) bool {
+	name, _, _ := parseModifier(comp, option.modifiers())
+	return option.modifiers().Exists(name)
+}
+
+// appendMatches appends the values of the component of a path in val.
+func appendMatches(vals []Result, val Result, comp string, option *PathOption) []Result {
+	if comp == "#" || isWildcard(comp) {
+		pattern := unescapeComp(comp)
+		val.ForEach(func(key, value Result) bool {
+			if comp == "#" && val.IsArray() || key.Type == String && Match(key.Str, pattern) {
+				vals = append(vals, value)
 			}
-			njson = append(njson, jstr[index+len(vres.Raw):]...)
-			jstr = string(njson)
+			return true
+		})
+		return vals
+	}
+	res := val.get(comp, option)
+	switch {
+	case strings.HasPrefix(comp, "#(") && strings.HasSuffix(comp, ")#"):
+		// the array of the values, which can be empty
+		res.ForEach(func(_, value Result) bool {
+			vals = append(vals, value)
+			return true
+		})
+	case res.Exists() && res.Index > 0:
+		vals = append(vals, res)
+	}
+	return vals
+}
+
+// isWildcard tells if a component of a path is a key with '*' or '?'.
+func isWildcard(comp string) bool {
+	if strings.HasPrefix(comp, "#(") {
+		return false
+	}
+	for i := 0; i < len(comp); i++ {
+		switch comp[i] {
+		case '\\':
+			i++
+		case '*', '?':
+			return true
+		}
+	}
+	return false
+}
+
+// unescapeComp removes the escape characters of a component of a path.
+func unescapeComp(comp string) string {
+	if strings.IndexByte(comp, '\\') < 0 {
+		return comp
+	}
+	b := make([]byte, 0, len(comp))
+	for i := 0; i < len(comp); i++ {
+		if comp[i] == '\\' && i+1 < len(comp) {
+			i++
 		}
+		b = append(b, comp[i])
 	}
-	return []byte(jstr), nil
+	return string(b)
+}
+
+// getValues returns the values that Get finds at the path, which must be in
+// the json.
+func getValues(jstr, path string, option *PathOption) ([]Result, error) {
+	res := getPath(jstr, path, option)
+	if !res.Exists() {
+		return nil, nil
+	}
+	var vals []Result
+	if res.Indexes == nil {
+		vals = []Result{res}
+	} else {
+		vals = make([]Result, 0, len(res.Indexes))
+		res.ForEach(func(_, vres Result) bool {
+			if len(vals) < len(res.Indexes) {
+				vres.Index = res.Indexes[len(vals)]
+			}
+			vals = append(vals, vres)
+			return true
+		})
+	}
+	invalid := res.Indexes != nil && len(vals) != len(res.Indexes)
+	for i := 0; i < len(vals) && !invalid; i++ {
+		invalid = !inJSON(jstr, vals[i])
+	}
+	if invalid {
+		return nil, &SetError{Err: ErrInvalidPath, Path: path, Reason: "cannot set a path whose values are not in the json"}
+	}
+	return vals, nil
+}
+
+// inJSON tells if the result is the value at its index in the json.
+func inJSON(jstr string, res Result) bool {
+	return res.Index > 0 && res.Index+len(res.Raw) <= len(jstr) && jstr[res.Index:res.Index+len(res.Raw)] == res.Raw
 }
 
 // Set sets a json value for the specified path.
@@ -586,6 +1128,19 @@ func setComplexPath(jstr, path, raw string, stringify bool) ([]byte, error) {
 //	"age"                >> 37
 //	"children.1"         >> "Alex"
 func Set(json, path string, value interface{}, options ...SetOptions) (string, error) {
+	res, _, err := SetAll(json, path, value, options...)
+	return res, err
+}
+
+// SetAll sets a json value for the specified path like Set, and returns the
+// number of values that are set. A path with wildcard keys, a '#' for all
+// the elements of an array, or a '#(...)#' query sets every value that it
+// matches:
+//
+//	"friends.#(age>45)#.active" >> each friend older than 45
+//	"friends.#.active"          >> each friend
+//	"prices.*.amount"           >> each member of prices
+func SetAll(json, path string, value interface{}, options ...SetOptions) (string, int, error) {
 	opts := SetOptions{}
 	if len(options) > 0 {
 		opts = options[0]
@@ -598,29 +1153,45 @@ func Set(json, path string, value interface{}, options ...SetOptions) (string, e
 	jsonh := *(*stringHeader)(unsafe.Pointer(&json))
 	jsonbh := sliceHeader{data: jsonh.data, len: jsonh.len, cap: jsonh.len}
 	jsonb := *(*[]byte)(unsafe.Pointer(&jsonbh))
-	res, err := SetBytes(jsonb, path, value, opts)
-	return string(res), err
+	res, n, err := setBytes(jsonb, path, value, opts)
+	return string(res), n, err
 }
 
 // SetBytes sets a json value for the specified path.
 // If working with bytes, this method preferred over
 // Set(string(data), path, value)
 func SetBytes(json []byte, path string, value interface{}, options ...SetOptions) ([]byte, error) {
-	sc := makeSetConfig(false, false, false, false)
+	res, _, err := setBytes(json, path, value, options...)
+	return res, err
+}
 
+func setBytes(json []byte, path string, value interface{}, options ...SetOptions) ([]byte, int, error) {
+	var opts SetOptions
 	if len(options) > 0 {
-		sc.optimistic = options[0].Optimistic
-		sc.inplace = options[0].ReplaceInPlace
-		sc.PathOption = options[0].PathOption
+		opts = options[0]
 	}
+	sc := opts.config()
 	jstr := *(*string)(unsafe.Pointer(&json))
-	var raw string
+	raw, err := valueRaw(value, &sc)
+	if err != nil {
+		return nil, 0, err
+	}
+	sc.mergeStruct = opts.MergeStruct && isStruct(value)
+	res, n, err := set(jstr, path, raw, sc)
+	if err == errNoChange {
+		return json, 0, nil
+	}
+	return res, n, err
+}
 
+// valueRaw converts a value of Set into its json representation, marking
+// the config for strings that need to be stringified and for deletes.
+func valueRaw(value interface{}, sc *setConfig) (raw string, err error) {
 	switch v := value.(type) {
 	default:
-		b, merr := jsongo.Marshal(value)
+		b, merr := sc.marshal(value)
 		if merr != nil {
-			return nil, merr
+			return "", merr
 		}
 		raw = *(*string)(unsafe.Pointer(&b))
 	case dtype:
@@ -634,6 +1205,8 @@ func SetBytes(json []byte, path string, value interface{}, options ...SetOptions
 		sc.stringify = true
 	case bool:
 		raw = If(v, "true", "false")
+	case int:
+		raw = strconv.Itoa(v)
 	case int8:
 		raw = strconv.FormatInt(int64(v), 10)
 	case int16:
@@ -642,6 +1215,8 @@ func SetBytes(json []byte, path string, value interface{}, options ...SetOptions
 		raw = strconv.FormatInt(int64(v), 10)
 	case int64:
 		raw = strconv.FormatInt(v, 10)
+	case uint:
+		raw = strconv.FormatUint(uint64(v), 10)
 	case uint8:
 		raw = strconv.FormatUint(uint64(v), 10)
 	case uint16:
@@ -655,12 +1230,50 @@ func SetBytes(json []byte, path string, value interface{}, options ...SetOptions
 	case float64:
 		raw = strconv.FormatFloat(v, 'f', -1, 64)
 	}
+	return raw, nil
+}
+
+// marshal marshals a value that is not a basic type.
+func (sc *setConfig) marshal(value interface{}) ([]byte, error) {
+	if sc.marshaler != nil {
+		b, err := sc.marshaler(value)
+		if err != nil {
+			return nil, err
+		}
+		if !ValidBytes(b) {
+			return nil, &SetError{Err: ErrInvalidJSON, Reason: "the marshaler returned invalid json"}
+		}
+		if !sc.escapeHTML {
+			return b, nil
+		}
+		var buf bytes.Buffer
+		jsongo.HTMLEscape(&buf, b)
+		return buf.Bytes(), nil
+	}
+	var buf bytes.Buffer
+	enc := jsongo.NewEncoder(&buf)
+	enc.SetEscapeHTML(sc.escapeHTML)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
 
-	res, err := set(jstr, path, raw, sc)
-	if err == errNoChange {
-		return json, nil
+// isStruct tells if the value is a struct or a pointer to a struct.
+func isStruct(value interface{}) bool {
+	return reflect.Indirect(reflect.ValueOf(value)).Kind() == reflect.Struct
+}
+
+// mergeStruct returns the raw json of a marshaled struct merged into the
+// current object, or the raw json itself when the current value is not an
+// object.
+func mergeStruct(cur Result, raw string) (string, error) {
+	fields := Parse(raw)
+	if !cur.IsObject() || !fields.IsObject() {
+		return raw, nil
 	}
-	return res, err
+	var m merger
+	return m.mergeObjects(cur.Raw, fields)
 }
 
 // If returns a if v is true, else returns b.
@@ -678,13 +1291,11 @@ func If(v bool, a, b string) string {
 func SetRawBytes(json []byte, path string, value []byte, options ...SetOptions) ([]byte, error) {
 	jstr := *(*string)(unsafe.Pointer(&json))
 	vstr := *(*string)(unsafe.Pointer(&value))
-	sc := makeSetConfig(false, false, false, false)
+	var opts SetOptions
 	if len(options) > 0 {
-		sc.optimistic = options[0].Optimistic
-		sc.inplace = options[0].ReplaceInPlace
+		opts = options[0]
 	}
-
-	res, err := set(jstr, path, vstr, sc)
+	res, _, err := set(jstr, path, vstr, opts.config())
 	if err == errNoChange {
 		return json, nil
 	}
@@ -2,6 +2,7 @@ package jj
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/rand"
 	"testing"
@@ -138,6 +139,12 @@ func TestDelete(t *testing.T) {
 	testRaw(t, setDelete, `{"this":"that"}`, `{"this":"that","and":"another"}`, `and`, nil)
 	testRaw(t, setDelete, `{}`, `{"and":"another"}`, `and`, nil)
 	testRaw(t, setDelete, `{"1":"2"}`, `{"1":"2"}`, `3`, nil)
+	testRaw(t, setDelete, `[1,4,5]`, `[1,2,3,4,5]`, `[1:3]`, nil)
+	testRaw(t, setDelete, `{"a":[1,2,3]}`, `{"a":[1,2,3,4,5]}`, `a.[-2:]`, nil)
+	testRaw(t, setDelete, `{"a":[2,4]}`, `{"a":[1,2,3,4,5]}`, `a.[::-2]`, nil)
+	testRaw(t, setDelete, `{"a":[]}`, `{"a":[1,2,3]}`, `a.[:]`, nil)
+	testRaw(t, setDelete, `{"a":[1,2,3]}`, `{"a":[1,2,3]}`, `a.[5:]`, nil)
+	testRaw(t, setDelete, `{"a":[{},{"y":2}]}`, `{"a":[{"x":1},{"x":2,"y":2}]}`, `a.[:].x`, nil)
 }
 
 // TestSjsonRandomData is a fuzzing test that throws random data at SetRaw
@@ -345,6 +352,23 @@ func TestIndexes2(t *testing.T) {
 	}
 }
 
+func TestSetSlice(t *testing.T) {
+	json, err := Set(example, "friends.[1:].last", "Johnson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Get(json, "friends.#.last").String() != `["Murphy","Johnson","Johnson"]` {
+		t.Fatal("mismatch")
+	}
+	json, err = Set(`{"a":[1,2,3,4,5]}`, "a.[::2]", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if json != `{"a":[0,2,0,4,0]}` {
+		t.Fatal("mismatch")
+	}
+}
+
 func TestIssue61(t *testing.T) {
 	json := `{
 		"@context": {
@@ -358,3 +382,259 @@ func TestIssue61(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestSetAll(t *testing.T) {
+	tests := []struct {
+		path   string
+		value  any
+		n      int
+		get    string
+		expect string
+	}{
+		{`friends.#(age>45)#.active`, false, 2, `friends.#.active`, `[false,false]`},
+		{`friends.#.active`, true, 3, `friends.#.active`, `[true,true,true]`},
+		{`friends.#.nets.#(=="tw")#`, "x", 3, `friends.#.nets`, `[["ig", "fb", "x"],["fb", "x"],["ig", "x"]]`},
+		{`name.*`, "X", 2, `name`, `{"first": "X", "last": "X"}`},
+		{`fav\.m*`, "Up", 1, `fav\.movie`, `"Up"`},
+		{`friends.#(last="Murphy").age`, 1, 1, `friends.#.age`, `[1,68,47]`},
+		{`friends.#(last="Nobody")#.age`, 1, 0, `friends.#.age`, `[44,68,47]`},
+		{`friends.#(age<45)#.nets.-1`, "li", 1, `friends.0.nets`, `["ig", "fb", "tw","li"]`},
+		{`friends.[:2].age`, 0, 2, `friends.#.age`, `[0,0,47]`},
+		// the missing keys after the matches are created
+		{`friends.#.x.y`, 9, 3, `friends.#.x`, `[{"y":9},{"y":9},{"y":9}]`},
+		{`friends.#(age>45)#.pet.name`, "Rex", 2, `friends.#.pet.name`, `["Rex","Rex"]`},
+		{`friends.#.nets.5`, "z", 3, `friends.0.nets`, `["ig","fb","tw",null,null,"z"]`},
+	}
+	for _, tt := range tests {
+		json, n, err := SetAll(example, tt.path, tt.value)
+		if err != nil || n != tt.n || Get(json, tt.get).Raw != tt.expect {
+			t.Fatalf("path '%v': expected '%v' %v, got '%v' %v %v", tt.path, tt.expect, tt.n, Get(json, tt.get).Raw, n, err)
+		}
+	}
+	for _, path := range []string{`friends.#(age>45)#.first.@this`, `friends.@reverse.#.age`, `name.*|@this`,
+		`friends.#.nets.#`, `friends.#(age>45)#.nets.#`, `name|last`, `**.age`, `friends.**.age`} {
+		json, n, err := SetAll(example, path, 1)
+		assert(t, errors.Is(err, ErrInvalidPath) && n == 0 && json == example)
+		json, n, err = DeleteAll(example, path)
+		assert(t, errors.Is(err, ErrInvalidPath) && n == 0 && json == example)
+	}
+	json := `{"a":[[1,2],[3,4]]}`
+	got, n, err := SetAll(json, "a.#.#", 7)
+	assert(t, errors.Is(err, ErrInvalidPath) && n == 0 && got == json)
+	got, n, err = DeleteAll(json, "a.#.#")
+	assert(t, errors.Is(err, ErrInvalidPath) && n == 0 && got == json)
+}
+
+func TestDeleteAll(t *testing.T) {
+	json := `{"items":[{"price":1},{"price":-1},{"price":2},{"price":-3}],"a":{"x1":1,"y":2,"x2":3}}`
+	tests := []struct {
+		path   string
+		n      int
+		expect string
+	}{
+		{`items.#(price<0)#`, 2, `{"items":[{"price":1},{"price":2}],"a":{"x1":1,"y":2,"x2":3}}`},
+		{`items.#.price`, 4, `{"items":[{},{},{},{}],"a":{"x1":1,"y":2,"x2":3}}`},
+		{`a.x?`, 2, `{"items":[{"price":1},{"price":-1},{"price":2},{"price":-3}],"a":{"y":2}}`},
+		{`*`, 2, `{}`},
+		{`items.#(price>5)#`, 0, json},
+		{`items.#.discount`, 0, json},
+	}
+	for _, tt := range tests {
+		got, n, err := DeleteAll(json, tt.path)
+		if err != nil || n != tt.n || got != tt.expect {
+			t.Fatalf("path '%v': expected '%v' %v, got '%v' %v %v", tt.path, tt.expect, tt.n, got, n, err)
+		}
+	}
+	got, err := Delete(json, `items.#(price<0)`)
+	assert(t, err == nil && got == `{"items":[{"price":1},{"price":2},{"price":-3}],"a":{"x1":1,"y":2,"x2":3}}`)
+}
+
+func TestSetMarshalOptions(t *testing.T) {
+	value := map[string]string{"h": "<b>&"}
+	json, err := Set(`{}`, "a", value)
+	assert(t, err == nil && json == `{"a":{"h":"<b>&"}}`)
+	json, err = Set(`{}`, "a", value, SetOptions{EscapeHTML: true})
+	assert(t, err == nil && json == `{"a":{"h":"\u003cb\u003e\u0026"}}`)
+
+	marshaler := func(v interface{}) ([]byte, error) {
+		return []byte(`{"h":"<custom>"}`), nil
+	}
+	json, err = Set(`{}`, "a", value, SetOptions{Marshaler: marshaler})
+	assert(t, err == nil && json == `{"a":{"h":"<custom>"}}`)
+	json, err = Set(`{}`, "a", value, SetOptions{Marshaler: marshaler, EscapeHTML: true})
+	assert(t, err == nil && json == `{"a":{"h":"\u003ccustom\u003e"}}`)
+	json, err = Set(`{}`, "a", 1, SetOptions{Marshaler: marshaler})
+	assert(t, err == nil && json == `{"a":1}`)
+
+	failing := func(v interface{}) ([]byte, error) {
+		return nil, errors.New("failed")
+	}
+	_, err = Set(`{}`, "a", value, SetOptions{Marshaler: failing})
+	assert(t, err != nil && err.Error() == "failed")
+
+	invalid := func(v interface{}) ([]byte, error) {
+		return []byte(`{bad`), nil
+	}
+	json, err = Set(`{"a":1}`, "b", value, SetOptions{Marshaler: invalid})
+	assert(t, errors.Is(err, ErrInvalidJSON))
+	_, _, err = SetMany([]byte(`{"a":1}`), []Update{{Path: "b", Value: value}}, SetOptions{Marshaler: invalid})
+	assert(t, errors.Is(err, ErrInvalidJSON))
+}
+
+func TestSetMergeStruct(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+	type user struct {
+		Name    string   `json:"name"`
+		Age     int      `json:"age,omitempty"`
+		Address *address `json:"address,omitempty"`
+	}
+	json := `{"user":{"id":7, "name":"Tom", "address":{"zip":"75001","city":"Lyon"}}}`
+	got, err := Set(json, "user", user{Name: "Sam", Address: &address{City: "Paris"}}, SetOptions{MergeStruct: true})
+	assert(t, err == nil && got == `{"user":{"id":7, "name":"Sam", "address":{"zip":"75001","city":"Paris"}}}`)
+
+	got, err = Set(json, "user", &user{Name: "Sam", Age: 3}, SetOptions{MergeStruct: true})
+	assert(t, err == nil && got == `{"user":{"id":7, "name":"Sam", "address":{"zip":"75001","city":"Lyon"},"age":3}}`)
+
+	got, err = Set(json, "user", user{Name: "Sam"})
+	assert(t, err == nil && got == `{"user":{"name":"Sam"}}`)
+
+	got, err = Set(json, "other", user{Name: "Sam"}, SetOptions{MergeStruct: true})
+	assert(t, err == nil && Get(got, "other").Raw == `{"name":"Sam"}`)
+
+	got, err = Set(json, "user", map[string]int{"id": 8}, SetOptions{MergeStruct: true})
+	assert(t, err == nil && got == `{"user":{"id":8}}`)
+
+	// each match is merged on its own
+	type s struct {
+		X int `json:"x"`
+	}
+	json = `{"m":{"a":{"x":1,"y":1},"b":{"x":2,"z":2},"c":3}}`
+	got, n, err := SetAll(json, "m.*", s{5}, SetOptions{MergeStruct: true})
+	assert(t, err == nil && n == 3 && got == `{"m":{"a":{"x":5,"y":1},"b":{"x":5,"z":2},"c":{"x":5}}}`)
+	got, n, err = SetAll(json, "m.*.w", s{5}, SetOptions{MergeStruct: true})
+	assert(t, err == nil && n == 2 && got == `{"m":{"a":{"x":1,"y":1,"w":{"x":5}},"b":{"x":2,"z":2,"w":{"x":5}},"c":3}}`)
+
+	b, _, err := SetMany([]byte(json), []Update{{Path: "m.a", Value: s{5}}, {Path: "m.b", Value: &s{6}}}, SetOptions{MergeStruct: true})
+	assert(t, err == nil && string(b) == `{"m":{"a":{"x":5,"y":1},"b":{"x":6,"z":2},"c":3}}`)
+	b, _, err = SetMany([]byte(json), []Update{{Path: "m.a", Value: `{"x":5}`, Raw: true}}, SetOptions{MergeStruct: true})
+	assert(t, err == nil && string(b) == `{"m":{"a":{"x":5},"b":{"x":2,"z":2},"c":3}}`)
+}
+
+func TestSetKeepsLayout(t *testing.T) {
+	json := "{\n  \"name\": \"Tom\",\n  \"tags\": [\n    \"a\"\n  ]\n}\n"
+	tests := []struct {
+		path   string
+		value  any
+		expect string
+	}{
+		{"age", 37, "{\n  \"name\": \"Tom\",\n  \"tags\": [\n    \"a\"\n  ],\n  \"age\": 37\n}\n"},
+		{"tags.-1", "b", "{\n  \"name\": \"Tom\",\n  \"tags\": [\n    \"a\",\n    \"b\"\n  ]\n}\n"},
+		{"tags.2", "c", "{\n  \"name\": \"Tom\",\n  \"tags\": [\n    \"a\",\n    null,\n    \"c\"\n  ]\n}\n"},
+		{"tags.[+0]", "z", "{\n  \"name\": \"Tom\",\n  \"tags\": [\n    \"z\",\n    \"a\"\n  ]\n}\n"},
+		{"a.b", 1, "{\n  \"name\": \"Tom\",\n  \"tags\": [\n    \"a\"\n  ],\n  \"a\": {\n    \"b\": 1\n  }\n}\n"},
+		{"tags.-1.b", 1, "{\n  \"name\": \"Tom\",\n  \"tags\": [\n    \"a\",\n    {\n      \"b\": 1\n    }\n  ]\n}\n"},
+	}
+	for _, tt := range tests {
+		got, err := Set(json, tt.path, tt.value)
+		if err != nil || got != tt.expect {
+			t.Fatalf("path '%v': expected '%q', got '%q' %v", tt.path, tt.expect, got, err)
+		}
+	}
+
+	crlf := "{\r\n\t\"a\" : 1\r\n}"
+	got, err := Set(crlf, "b.c", 2)
+	assert(t, err == nil && got == "{\r\n\t\"a\" : 1,\r\n\t\"b\" : {\r\n\t\t\"c\": 2\r\n\t}\r\n}")
+}
+
+func TestDeleteKeepsLayout(t *testing.T) {
+	json := "{\n  \"a\": 1,\n  \"b\": [\n    1,\n    2\n  ],\n  \"c\": {\n    \"d\": 1\n  }\n}"
+	tests := []struct {
+		path   string
+		expect string
+	}{
+		{"a", "{\n  \"b\": [\n    1,\n    2\n  ],\n  \"c\": {\n    \"d\": 1\n  }\n}"},
+		{"c", "{\n  \"a\": 1,\n  \"b\": [\n    1,\n    2\n  ]\n}"},
+		{"b.0", "{\n  \"a\": 1,\n  \"b\": [\n    2\n  ],\n  \"c\": {\n    \"d\": 1\n  }\n}"},
+		{"b.1", "{\n  \"a\": 1,\n  \"b\": [\n    1\n  ],\n  \"c\": {\n    \"d\": 1\n  }\n}"},
+		{"c.d", "{\n  \"a\": 1,\n  \"b\": [\n    1,\n    2\n  ],\n  \"c\": {}\n}"},
+	}
+	for _, tt := range tests {
+		got, err := Delete(json, tt.path)
+		if err != nil || got != tt.expect {
+			t.Fatalf("path '%v': expected '%q', got '%q' %v", tt.path, tt.expect, got, err)
+		}
+	}
+}
+
+func TestSetIf(t *testing.T) {
+	json := `{"version":3,"meta":{"a":1,"b":[1.0]}}`
+	got, err := SetIf(json, "version", "3", 4)
+	assert(t, err == nil && got == `{"version":4,"meta":{"a":1,"b":[1.0]}}`)
+	got, err = SetIf(json, "meta", `{"b":[1],"a":1}`, "x")
+	assert(t, err == nil && got == `{"version":3,"meta":"x"}`)
+
+	got, err = SetIf(json, "version", "2", 4)
+	assert(t, errors.Is(err, ErrConflict) && got == json)
+	var se *SetError
+	assert(t, errors.As(err, &se) && se.Path == "version")
+	got, err = SetIf(json, "missing", "2", 4)
+	assert(t, errors.Is(err, ErrPathNotFound) && got == json)
+	assert(t, err.Error() == `path not found "missing"`)
+
+	json = `{"list":[{"v":1},{"v":2}],"m":{"a":1,"b":1}}`
+	for _, path := range []string{"list.#.v", "m.*", "list.#(v>0)#.v", "m|a", "list.#(v==1)|v"} {
+		got, err = SetIf(json, path, "1", 3)
+		assert(t, errors.Is(err, ErrInvalidPath) && got == json)
+	}
+	got, err = SetIf(json, "list.0.v", "1", 3)
+	assert(t, err == nil && got == `{"list":[{"v":3},{"v":2}],"m":{"a":1,"b":1}}`)
+	got, err = SetIf(json, "list.#(v==2).v", "2", 3)
+	assert(t, err == nil && got == `{"list":[{"v":1},{"v":3}],"m":{"a":1,"b":1}}`)
+}
+
+func TestSetIfAbsentPresent(t *testing.T) {
+	json := `{"a":1,"list":[{"id":1},{"id":2,"x":0}]}`
+	absent, present := SetOptions{IfAbsent: true}, SetOptions{IfPresent: true}
+
+	got, err := Set(json, "b", 2, absent)
+	assert(t, err == nil && got == `{"a":1,"list":[{"id":1},{"id":2,"x":0}],"b":2}`)
+	got, err = Set(json, "a", 2, absent)
+	assert(t, errors.Is(err, ErrConflict) && got == json)
+	got, err = SetRaw(json, "a", "2", absent)
+	assert(t, errors.Is(err, ErrConflict) && got == json)
+
+	got, err = Set(json, "a", 2, present)
+	assert(t, err == nil && got == `{"a":2,"list":[{"id":1},{"id":2,"x":0}]}`)
+	got, err = Set(json, "b", 2, present)
+	assert(t, errors.Is(err, ErrPathNotFound) && got == json)
+	_, err = Delete(json, "b", present)
+	assert(t, errors.Is(err, ErrPathNotFound))
+	got, err = Delete(json, "b")
+	assert(t, err == nil && got == json)
+
+	// the values of a path of many values that do not meet the condition
+	// are skipped
+	got, n, err := SetAll(json, "list.#.x", 1, absent)
+	assert(t, err == nil && n == 1 && got == `{"a":1,"list":[{"id":1,"x":1},{"id":2,"x":0}]}`)
+	got, n, err = SetAll(json, "list.#.x", 1, present)
+	assert(t, err == nil && n == 1 && got == `{"a":1,"list":[{"id":1},{"id":2,"x":1}]}`)
+
+	// and it fails when none is set
+	got, n, err = SetAll(json, "list.#.id", 1, absent)
+	assert(t, errors.Is(err, ErrConflict) && n == 0 && got == json)
+	got, n, err = SetAll(json, "list.#.y", 1, present)
+	assert(t, errors.Is(err, ErrPathNotFound) && n == 0 && got == json)
+}
+
+func TestSetErrors(t *testing.T) {
+	_, err := Set(`{}`, "", 1)
+	assert(t, errors.Is(err, ErrInvalidPath) && err.Error() == "path cannot be empty")
+	_, err = Set(`[1]`, "a", 1)
+	assert(t, errors.Is(err, ErrInvalidPath) && err.Error() == "cannot set array element for non-numeric key 'a'")
+	var se *SetError
+	assert(t, errors.As(err, &se) && se.Path == "a")
+	_, _, err = SetMany([]byte(`{}`), []Update{{Path: ""}})
+	assert(t, errors.Is(err, ErrInvalidPath))
+}
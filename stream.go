@@ -0,0 +1,721 @@
+package jj
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// streamParser reads json from an io.Reader one byte at a time, so that
+// only the values that are asked for need to be kept in memory.
+type streamParser struct {
+	rd   *bufio.Reader
+	off  int64  // the offset of the next byte in the stream
+	keep bool   // append the bytes that are read to buf
+	buf  []byte // the captured raw value
+	tok  []byte // the current string or scalar token
+	err  error  // a read error found by peek
+}
+
+func newStreamParser(r io.Reader) *streamParser {
+	return &streamParser{rd: bufio.NewReader(r)}
+}
+
+func (s *streamParser) readByte() (byte, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	c, err := s.rd.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	s.off++
+	if s.keep {
+		s.buf = append(s.buf, c)
+	}
+	return c, nil
+}
+
+// peek returns the next byte without reading it. The ok is false at the
+// end of the stream.
+func (s *streamParser) peek() (byte, bool) {
+	if s.err != nil {
+		return 0, false
+	}
+	b, err := s.rd.Peek(1)
+	if err != nil {
+		if err != io.EOF {
+			s.err = err
+		}
+		return 0, false
+	}
+	return b[0], true
+}
+
+// next reads the byte that peek returned, appending it to the token.
+func (s *streamParser) next(keep bool) {
+	c, _ := s.readByte()
+	if keep {
+		s.tok = append(s.tok, c)
+	}
+}
+
+// skipWS returns the first byte that is not whitespace.
+func (s *streamParser) skipWS() (byte, error) {
+	for {
+		c, err := s.readByte()
+		if err != nil || !isws(c) {
+			return c, err
+		}
+	}
+}
+
+// more is skipWS inside of a value, where the stream must not end.
+func (s *streamParser) more() (byte, error) {
+	c, err := s.skipWS()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return c, err
+}
+
+func (s *streamParser) syntaxError() error {
+	return &errorType{"invalid json at offset " + strconv.FormatInt(s.off-1, 10)}
+}
+
+type streamFn func(start int64, data []byte, info int) int
+
+// value reads the value starting with the byte c, which is already read.
+// The f is called the same way as the iter of StreamParse, and stop is true
+// when f returned 0.
+func (s *streamParser) value(c byte, dinfo int, f streamFn) (stop bool, err error) {
+	start := s.off - 1
+	var info int
+	switch {
+	case c == '{' || c == '[':
+		kind, closing := TokObject, byte('}')
+		if c == '[' {
+			kind, closing = TokArray, ']'
+		}
+		f2 := f
+		if f != nil {
+			r := f(start, []byte{c}, kind|TokOpen|dinfo)
+			if r == 0 {
+				return true, nil
+			}
+			if r == -1 {
+				f2 = nil
+			}
+		}
+		if c == '{' {
+			stop, err = s.object(f2)
+		} else {
+			stop, err = s.array(f2)
+		}
+		if stop || err != nil {
+			return stop, err
+		}
+		if f != nil {
+			if dinfo&TokStart == TokStart {
+				dinfo &= ^TokStart
+				dinfo |= TokEnd
+			}
+			if f(s.off-1, []byte{closing}, kind|TokClose|dinfo) == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	case c == '"':
+		info, err = s.str(f != nil)
+		info |= TokString
+	case c == '-' || isnum(c):
+		info, err = s.number(c, f != nil)
+		info |= TokNumber
+	case c == 't':
+		err = s.literal("true")
+		info = TokTrue
+	case c == 'f':
+		err = s.literal("false")
+		info = TokFalse
+	case c == 'n':
+		err = s.literal("null")
+		info = TokNull
+	default:
+		return false, s.syntaxError()
+	}
+	if err != nil {
+		return false, err
+	}
+	if f != nil {
+		if dinfo&TokStart == TokStart {
+			dinfo |= TokEnd
+		}
+		if f(start, s.tok, info|dinfo) == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *streamParser) object(f streamFn) (stop bool, err error) {
+	c, err := s.more()
+	if err != nil || c == '}' {
+		return false, err
+	}
+	for {
+		if c != '"' {
+			return false, s.syntaxError()
+		}
+		start := s.off - 1
+		info, err := s.str(f != nil)
+		if err != nil {
+			return false, err
+		}
+		if f != nil && f(start, s.tok, info|TokKey|TokString) == 0 {
+			return true, nil
+		}
+		if c, err = s.more(); err != nil {
+			return false, err
+		}
+		if c != ':' {
+			return false, s.syntaxError()
+		}
+		if f != nil && f(s.off-1, []byte{':'}, TokColon) == 0 {
+			return true, nil
+		}
+		if c, err = s.more(); err != nil {
+			return false, err
+		}
+		if stop, err = s.value(c, TokValue, f); stop || err != nil {
+			return stop, err
+		}
+		if c, err = s.more(); err != nil || c == '}' {
+			return false, err
+		}
+		if c != ',' {
+			return false, s.syntaxError()
+		}
+		if f != nil && f(s.off-1, []byte{','}, TokComma) == 0 {
+			return true, nil
+		}
+		if c, err = s.more(); err != nil {
+			return false, err
+		}
+	}
+}
+
+func (s *streamParser) array(f streamFn) (stop bool, err error) {
+	c, err := s.more()
+	if err != nil || c == ']' {
+		return false, err
+	}
+	for {
+		if stop, err = s.value(c, TokValue, f); stop || err != nil {
+			return stop, err
+		}
+		if c, err = s.more(); err != nil || c == ']' {
+			return false, err
+		}
+		if c != ',' {
+			return false, s.syntaxError()
+		}
+		if f != nil && f(s.off-1, []byte{','}, TokComma) == 0 {
+			return true, nil
+		}
+		if c, err = s.more(); err != nil {
+			return false, err
+		}
+	}
+}
+
+// str reads a string, the opening '"' is already read. The token, with the
+// quotes, is only kept when keep is true.
+func (s *streamParser) str(keep bool) (info int, err error) {
+	s.tok = append(s.tok[:0], '"')
+	for {
+		c, err := s.readByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return info, err
+		}
+		if keep {
+			s.tok = append(s.tok, c)
+		}
+		switch {
+		case c == '"':
+			return info, nil
+		case c < ' ':
+			return info, s.syntaxError()
+		case c == '\\':
+			info |= TokEscaped
+			c, ok := s.peek()
+			if !ok {
+				return info, io.ErrUnexpectedEOF
+			}
+			switch c {
+			default:
+				return info, s.syntaxError()
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				s.next(keep)
+			case 'u':
+				s.next(keep)
+				for j := 0; j < 4; j++ {
+					c, ok := s.peek()
+					if !ok {
+						return info, io.ErrUnexpectedEOF
+					}
+					if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')) {
+						return info, s.syntaxError()
+					}
+					s.next(keep)
+				}
+			}
+		}
+	}
+}
+
+// number reads a number starting with the byte c.
+func (s *streamParser) number(c byte, keep bool) (info int, err error) {
+	s.tok = append(s.tok[:0], c)
+	digits := func(min int) error {
+		var n int
+		for {
+			c, ok := s.peek()
+			if !ok || !isnum(c) {
+				break
+			}
+			s.next(keep)
+			n++
+		}
+		if n < min {
+			return s.syntaxError()
+		}
+		return nil
+	}
+	if c == '-' {
+		info |= TokSign
+		if c, _ = s.peek(); !isnum(c) {
+			return info, s.syntaxError()
+		}
+		s.next(keep)
+	}
+	if c != '0' {
+		if err = digits(0); err != nil {
+			return info, err
+		}
+	}
+	if c, _ = s.peek(); c == '.' {
+		info |= TokDot
+		s.next(keep)
+		if err = digits(1); err != nil {
+			return info, err
+		}
+	}
+	if c, _ = s.peek(); c == 'e' || c == 'E' {
+		info |= TokE
+		s.next(keep)
+		if c, _ = s.peek(); c == '+' || c == '-' {
+			s.next(keep)
+		}
+		if err = digits(1); err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+// literal reads the rest of true, false or null.
+func (s *streamParser) literal(lit string) error {
+	for i := 1; i < len(lit); i++ {
+		c, err := s.readByte()
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+		if c != lit[i] {
+			return s.syntaxError()
+		}
+	}
+	s.tok = append(s.tok[:0], lit...)
+	return nil
+}
+
+// StreamParseReader is StreamParse for a json document read from r in
+// chunks, so that a document larger than memory can be parsed.
+// The 'start' param of iter is the offset of the element in the stream, and
+// 'data' is the complete element data, which is only valid until iter
+// returns.
+// Returning 0, 1 or -1 from iter works the same as with StreamParse.
+//
+// The number of bytes read from r is returned, with an error for an
+// invalid document or a failed read.
+func StreamParseReader(r io.Reader, iter func(start int64, data []byte, info int) int) (int64, error) {
+	s := newStreamParser(r)
+	c, err := s.more()
+	if err != nil {
+		return s.off, err
+	}
+	if stop, err := s.value(c, TokStart, iter); stop || err != nil {
+		return s.off, err
+	}
+	for {
+		c, err := s.readByte()
+		if err == io.EOF {
+			return s.off, nil
+		}
+		if err != nil {
+			return s.off, err
+		}
+		if !isws(c) {
+			return s.off, s.syntaxError()
+		}
+	}
+}
+
+// simpleComp is a plain key or index of a path.
+type simpleComp struct {
+	key  string // the unescaped key
+	path string // the path from this component on
+}
+
+// simpleComps splits a path into the leading plain keys and indexes, which
+// can be followed without the path parser, like in a stream, and the rest,
+// which is applied to the value that the keys lead to.
+func simpleComps(path string, rawPath bool) (comps []simpleComp, rest string) {
+	if rawPath {
+		return []simpleComp{{key: path, path: path}}, ""
+	}
+	for i := 0; i < len(path); {
+		key, j, ok := nextSimpleComp(path, i)
+		if !ok {
+			return comps, path[i:]
+		}
+		comps = append(comps, simpleComp{key: key, path: path[i:]})
+		if j == len(path) {
+			return comps, ""
+		}
+		if i = j + 1; i == len(path) {
+			return comps, path[j:]
+		}
+	}
+	return comps, ""
+}
+
+// nextSimpleComp reads the plain key or index of the path at i, up to the
+// '.' or '|' at j, or the end of the path. The ok is false when the
+// component is empty, or has any of the path syntax.
+func nextSimpleComp(path string, i int) (key string, j int, ok bool) {
+	var esc []byte
+	for j = i; j < len(path); j++ {
+		c := path[j]
+		if c == '\\' && j+1 < len(path) {
+			if esc == nil {
+				esc = append(make([]byte, 0, len(path)-i), path[i:j]...)
+			}
+			j++
+			esc = append(esc, path[j])
+			continue
+		}
+		if c == '.' || c == '|' {
+			break
+		}
+		if c < ' ' || !isSafePathKeyChar(c) {
+			return "", j, false
+		}
+		if esc != nil {
+			esc = append(esc, c)
+		}
+	}
+	if esc != nil {
+		return string(esc), j, true
+	}
+	return path[i:j], j, j > i
+}
+
+// follow reads the value starting with the byte c, and returns the value
+// that the comps and the rest of the path lead to. The whole value is read
+// even when the result is found, which leaves the stream after it.
+func (s *streamParser) follow(c byte, comps []simpleComp, rest string, optionsFns []PathOptionFn) (Result, error) {
+	if len(comps) == 0 {
+		return s.capture(c, rest, optionsFns)
+	}
+	comp := comps[0]
+	var res Result
+	switch c {
+	default:
+		_, err := s.value(c, 0, nil)
+		return res, err
+	case '[':
+		n, err := strconv.Atoi(comp.key)
+		if err == nil && n < 0 {
+			// a negative index needs the whole array
+			return s.capture(c, comp.path, optionsFns)
+		}
+		if err != nil {
+			_, err := s.value(c, 0, nil)
+			return res, err
+		}
+		if c, err = s.more(); err != nil || c == ']' {
+			return res, err
+		}
+		for i := 0; ; i++ {
+			if i == n {
+				res, err = s.follow(c, comps[1:], rest, optionsFns)
+			} else {
+				_, err = s.value(c, TokValue, nil)
+			}
+			if err != nil {
+				return res, err
+			}
+			if c, err = s.more(); err != nil || c == ']' {
+				return res, err
+			}
+			if c != ',' {
+				return res, s.syntaxError()
+			}
+			if c, err = s.more(); err != nil {
+				return res, err
+			}
+		}
+	case '{':
+		c, err := s.more()
+		if err != nil || c == '}' {
+			return res, err
+		}
+		for {
+			if c != '"' {
+				return res, s.syntaxError()
+			}
+			info, err := s.str(true)
+			if err != nil {
+				return res, err
+			}
+			key := string(s.tok[1 : len(s.tok)-1])
+			if info&TokEscaped == TokEscaped {
+				key = unescape(key)
+			}
+			if c, err = s.more(); err != nil {
+				return res, err
+			}
+			if c != ':' {
+				return res, s.syntaxError()
+			}
+			if c, err = s.more(); err != nil {
+				return res, err
+			}
+			if !res.Exists() && key == comp.key {
+				// a later duplicate key is followed when the path is not
+				// found under the first one, like Get does
+				res, err = s.follow(c, comps[1:], rest, optionsFns)
+			} else {
+				_, err = s.value(c, TokValue, nil)
+			}
+			if err != nil {
+				return res, err
+			}
+			if c, err = s.more(); err != nil || c == '}' {
+				return res, err
+			}
+			if c != ',' {
+				return res, s.syntaxError()
+			}
+			if c, err = s.more(); err != nil {
+				return res, err
+			}
+		}
+	}
+}
+
+// capture keeps the value starting with the byte c in memory, and gets
+// the path from it. The empty path returns the value itself. The Index of
+// the result is the offset in the stream.
+func (s *streamParser) capture(c byte, path string, optionsFns []PathOptionFn) (Result, error) {
+	start := s.off - 1
+	s.keep = true
+	s.buf = append(s.buf[:0], c)
+	_, err := s.value(c, 0, nil)
+	s.keep = false
+	if err != nil {
+		return Result{}, err
+	}
+	raw := string(s.buf)
+	if path == "" {
+		res := Parse(raw)
+		res.Index = int(start)
+		return res, nil
+	}
+	res := Get(raw, path, optionsFns...)
+	if res.Indexes != nil {
+		for i := range res.Indexes {
+			res.Indexes[i] += int(start)
+		}
+	} else if res.Index > 0 {
+		res.Index += int(start)
+	}
+	return res, nil
+}
+
+// Reader gets values from a stream of json documents, like JSON Lines, that
+// is read in chunks. Only the values that the path leads to are kept in
+// memory, so the stream may be larger than memory.
+type Reader struct {
+	s *streamParser
+}
+
+// NewReader returns a Reader reading from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{s: newStreamParser(r)}
+}
+
+// Get reads the next json document from the stream, and searches it for
+// the path, the same way as Get does. The leading keys and indexes of the
+// path, like "friends.1.nets", are followed while reading; the document is
+// kept in memory from the first component that is not a plain key or index
+// on, like the "#" of "friends.#.first", or from a modifier. A later
+// duplicate key is followed when the path is not found under the first one.
+//
+// The Index of the result is the offset of the value in the stream. io.EOF
+// is returned when no documents are left.
+func (r *Reader) Get(path string, optionsFns ...PathOptionFn) (Result, error) {
+	if path == "" {
+		return Result{}, r.skip()
+	}
+	return r.get(path, optionsFns)
+}
+
+func (r *Reader) get(path string, optionsFns []PathOptionFn) (Result, error) {
+	c, err := r.s.skipWS()
+	if err != nil {
+		return Result{}, err
+	}
+	option := GetOptionFns(optionsFns).Apply(&PathOption{})
+	var piped string
+	if cuts, serr := scanPath(path); !option.RawPath && serr == nil {
+		// the path after the first pipe is applied to the value before it,
+		// so the later duplicate keys are only followed before the pipe
+		for _, cut := range cuts {
+			if path[cut] == '|' {
+				path, piped = path[:cut], path[cut+1:]
+				break
+			}
+		}
+	}
+	comps, rest := simpleComps(path, option.RawPath)
+	res, err := r.s.follow(c, comps, rest, optionsFns)
+	if err == nil {
+		err = r.s.err
+	}
+	if err != nil || piped == "" {
+		return res, err
+	}
+	pres := Get(res.Raw, piped, optionsFns...)
+	if res.Index == 0 {
+		// the value before the pipe is not at a known offset
+		pres.Index, pres.Indexes = 0, nil
+	} else if pres.Indexes != nil {
+		for i := range pres.Indexes {
+			pres.Indexes[i] += res.Index
+		}
+	} else if pres.Index > 0 {
+		pres.Index += res.Index
+	}
+	return pres, nil
+}
+
+// skip reads the next document.
+func (r *Reader) skip() error {
+	c, err := r.s.skipWS()
+	if err != nil {
+		return err
+	}
+	if _, err = r.s.value(c, 0, nil); err == nil {
+		err = r.s.err
+	}
+	return err
+}
+
+// GetReader searches the json document read from r for the path. See
+// Reader.Get, which reads the stream in chunks and only keeps the values
+// that the path leads to in memory.
+//
+// The '..' paths of JSON Lines, like "..#", "..3.name" and "..#.name", are
+// streamed too, one document at a time. Other '..' paths, like the queries
+// "..#(name="Tom")", keep the whole stream in memory.
+func GetReader(r io.Reader, path string, optionsFns ...PathOptionFn) (Result, error) {
+	rd := NewReader(r)
+	option := GetOptionFns(optionsFns).Apply(&PathOption{})
+	var res Result
+	var err error
+	if !option.RawPath && strings.HasPrefix(path, "..") {
+		res, err = rd.getLines(path[2:], optionsFns)
+	} else {
+		res, err = rd.Get(path, optionsFns...)
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return res, err
+}
+
+// getLines gets a '..' path, with the leading '..' removed, from a stream of
+// JSON Lines.
+func (r *Reader) getLines(path string, optionsFns []PathOptionFn) (Result, error) {
+	var n int
+	for n < len(path) && isnum(path[n]) {
+		n++
+	}
+	switch {
+	case n > 0 && (n == len(path) || path[n] == '.' || path[n] == '|'):
+		idx, err := strconv.Atoi(path[:n])
+		if err != nil {
+			return Result{}, err
+		}
+		for i := 0; i < idx; i++ {
+			if err := r.skip(); err != nil {
+				return Result{}, err
+			}
+		}
+		if n == len(path) {
+			return r.get("", optionsFns)
+		}
+		return r.get(path[n+1:], optionsFns)
+	case path == "#":
+		var count int
+		for {
+			if err := r.skip(); err == io.EOF {
+				return Result{Type: Number, Raw: strconv.Itoa(count), Num: float64(count)}, nil
+			} else if err != nil {
+				return Result{}, err
+			}
+			count++
+		}
+	case strings.HasPrefix(path, "#.") && !strings.Contains(path, "|"):
+		raw := []byte{'['}
+		for {
+			res, err := r.get(path[2:], optionsFns)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return Result{}, err
+			}
+			if res.Exists() {
+				if len(raw) > 1 {
+					raw = append(raw, ',')
+				}
+				raw = append(raw, res.Raw...)
+			}
+		}
+		return Result{Type: JSON, Raw: string(append(raw, ']'))}, nil
+	}
+	data, err := io.ReadAll(r.s.rd)
+	if err != nil {
+		return Result{}, err
+	}
+	return Get(string(data), ".."+path, optionsFns...), nil
+}
@@ -0,0 +1,135 @@
+package jj
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestStreamParseReader(t *testing.T) {
+	type token struct {
+		data string
+		info int
+	}
+	for _, json := range []string{
+		readmeJSON, `"a\"éb"`, `-1.5e+10`, `0`, ` [true,false,null,{},[]] `,
+		`{"a":{"b":[1,-0.5,"x"]},"c\\d":2E3}`,
+	} {
+		for _, ret := range []int{1, -1} {
+			var expect, got []token
+			StreamParse([]byte(json), func(start, end, info int) int {
+				expect = append(expect, token{json[start:end], info})
+				return ret
+			})
+			n, err := StreamParseReader(iotest.OneByteReader(strings.NewReader(json)),
+				func(start int64, data []byte, info int) int {
+					assert(t, json[start:int(start)+len(data)] == string(data))
+					got = append(got, token{string(data), info})
+					return ret
+				})
+			assert(t, err == nil && n == int64(len(json)))
+			if len(got) != len(expect) {
+				t.Fatalf("json '%v': expected '%v', got '%v'", json, expect, got)
+			}
+			for i := range got {
+				assert(t, got[i] == expect[i])
+			}
+		}
+	}
+	var keys int
+	_, err := StreamParseReader(strings.NewReader(readmeJSON), func(_ int64, _ []byte, info int) int {
+		if IsToken(info, TokKey) {
+			keys++
+			return 0
+		}
+		return 1
+	})
+	assert(t, err == nil && keys == 1)
+	for _, json := range []string{``, `{`, `{"a":}`, `[1,]`, `"a`, `01`, `1.`, `-`, `tru`, `{} x`, `"\x"`} {
+		_, err := StreamParseReader(strings.NewReader(json), func(int64, []byte, int) int { return 1 })
+		if err == nil {
+			t.Fatalf("json '%v': expected an error", json)
+		}
+	}
+}
+
+func TestGetReader(t *testing.T) {
+	paths := []string{
+		"name.last", "age", "children", "children.1", "children.-1", "children.#",
+		`fav\.movie`, "friends.1.nets.0", "friends.#.first", "friends.#(last=Murphy)#.first",
+		"friends.1|nets|@reverse", "friends.0.nothing", "name.first.x", "children.x",
+		"friends.1.nets.5", "@this.age", "{name.first,age}", "friends.01.first",
+	}
+	for _, path := range paths {
+		expect := Get(readmeJSON, path)
+		res, err := GetReader(iotest.OneByteReader(strings.NewReader(readmeJSON)), path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Raw != expect.Raw {
+			t.Fatalf("path '%v': expected '%v', got '%v'", path, expect.Raw, res.Raw)
+		}
+		if res.Index > 0 {
+			assert(t, readmeJSON[res.Index:res.Index+len(res.Raw)] == res.Raw)
+		}
+		for i, idx := range res.Indexes {
+			assert(t, strings.HasPrefix(readmeJSON[idx:], res.Array()[i].Raw))
+		}
+	}
+	// the later duplicate keys are followed like Get does, up to a pipe
+	dups := `{"x":{"y":1},"x":{"z":2},"y":{"a":1},"y":[3]}`
+	for _, path := range []string{"x.z", "x|z", "x.y", "y.0", "y|0", "y.#", "y|#", "x.z|@this"} {
+		expect := Get(dups, path)
+		res, err := GetReader(strings.NewReader(dups), path)
+		if err != nil || res.Raw != expect.Raw {
+			t.Fatalf("path '%v': expected '%v', got '%v' %v", path, expect.Raw, res.Raw, err)
+		}
+	}
+	res, err := GetReader(strings.NewReader(`{"x":{"y":1},"x":{"z":2}}`), "x.z")
+	assert(t, err == nil && res.Raw == "2" && res.Index == 22)
+
+	res, err = GetReader(strings.NewReader(`{"a.b":1}`), "a.b", WithRawPath(true))
+	assert(t, err == nil && res.Raw == "1")
+	res, err = GetReader(strings.NewReader(``), "a")
+	assert(t, err == nil && !res.Exists())
+	_, err = GetReader(strings.NewReader(`{"a":1,"b":[}`), "a")
+	assert(t, err != nil)
+	_, err = GetReader(strings.NewReader(`{"a":1,"b":[`), "a")
+	assert(t, err == io.ErrUnexpectedEOF)
+
+	// only the value of the path is kept in memory
+	rd := NewReader(strings.NewReader(`{"a":"` + strings.Repeat("x", 1<<20) + `","b":[1,2]}`))
+	res, err = rd.Get("b")
+	assert(t, err == nil && res.Raw == "[1,2]" && cap(rd.s.buf) < 1024 && cap(rd.s.tok) < 1024)
+}
+
+func TestReaderLines(t *testing.T) {
+	lines := "{\"name\":\"Gilbert\",\"age\":61}\n{\"name\":\"Alexa\",\"age\":34}\n" +
+		"{\"name\":\"May\",\"age\":57}\n{\"nick\":\"Deloise\"}\n"
+	for _, path := range []string{
+		"..#", "..0", "..2.name", "..1|age", "..#.name", "..#.age", "..#(age>50)#.name",
+		"..9", "..#(name=May).age",
+	} {
+		expect := Get(lines, path)
+		res, err := GetReader(iotest.OneByteReader(strings.NewReader(lines)), path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Raw != expect.Raw {
+			t.Fatalf("path '%v': expected '%v', got '%v'", path, expect.Raw, res.Raw)
+		}
+	}
+
+	var names []string
+	rd := NewReader(strings.NewReader(lines))
+	for {
+		res, err := rd.Get("name")
+		if err == io.EOF {
+			break
+		}
+		assert(t, err == nil)
+		names = append(names, res.String())
+	}
+	assert(t, strings.Join(names, ",") == "Gilbert,Alexa,May,")
+}
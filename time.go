@@ -0,0 +1,189 @@
+package jj
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bingoohuang/ngg/tick"
+)
+
+// TimeLayouts are the layouts tried by TimeAuto, and by TimeLayout when it
+// is called without layouts. A layout is a Go layout, or a Java style one
+// like "yyyy-MM-dd HH:mm:ss" as used by @random_time.
+var TimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"20060102150405",
+	"20060102",
+	"2006年01月02日 15时04分05秒",
+	"2006年01月02日 15:04:05",
+	"2006年1月2日 15:04:05",
+	"2006年01月02日",
+	"2006年1月2日",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.ANSIC,
+}
+
+// goLayouts caches the Go layouts of the Java style ones.
+var goLayouts sync.Map
+
+func goLayout(layout string) string {
+	if v, ok := goLayouts.Load(layout); ok {
+		return v.(string)
+	}
+	v := tick.ToLayout(layout)
+	goLayouts.Store(layout, v)
+	return v
+}
+
+// parseTime parses s with the first layout that matches. The times without
+// a zone are in loc.
+func parseTime(s string, layouts []string, loc *time.Location) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range layouts {
+		if tm, err := time.ParseInLocation(goLayout(layout), s, loc); err == nil {
+			return tm, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// epochTime returns the time of an epoch, which is in seconds, millis,
+// micros or nanos by its magnitude. Up to 1e11 are seconds, which are the
+// years up to 5138 and from 1973 on in millis.
+func (t Result) epochTime() (time.Time, bool) {
+	f := t.Float()
+	if t.Type == String {
+		var err error
+		if f, err = strconv.ParseFloat(t.Str, 64); err != nil {
+			return time.Time{}, false
+		}
+	}
+	n, exact := t.Int64OK()
+	abs := math.Abs(f)
+	switch {
+	case math.IsInf(f, 0) || math.IsNaN(f) || abs >= 1e19:
+		return time.Time{}, false
+	case abs < 1e11:
+		if exact {
+			return time.Unix(n, 0), true
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(math.Round(frac*1e9))), true
+	case !exact:
+		n = int64(f)
+	}
+	switch {
+	case abs < 1e14:
+		return time.UnixMilli(n), true
+	case abs < 1e17:
+		return time.UnixMicro(n), true
+	}
+	return time.Unix(0, n), true
+}
+
+// TimeLayout returns the time of a string parsed with the first of the
+// layouts that matches, or with the TimeLayouts when there are none. The
+// times without a zone are local. It returns the zero time when no layout
+// matches.
+func (t Result) TimeLayout(layouts ...string) time.Time {
+	if len(layouts) == 0 {
+		layouts = TimeLayouts
+	}
+	tm, _ := parseTime(t.String(), layouts, time.Local)
+	return tm
+}
+
+// TimeAuto returns the time of a timestamp in any of the TimeLayouts, or of
+// an epoch number in seconds, millis, micros or nanos, which is detected by
+// its magnitude. A String holding an epoch works too. It returns the zero
+// time for the other values.
+func (t Result) TimeAuto() time.Time {
+	tm, _ := t.timeAuto(TimeLayouts, time.Local)
+	return tm
+}
+
+func (t Result) timeAuto(layouts []string, loc *time.Location) (time.Time, bool) {
+	switch t.Type {
+	case Number:
+		return t.epochTime()
+	case String:
+		if tm, ok := parseTime(t.Str, layouts, loc); ok {
+			return tm, true
+		}
+		return t.epochTime()
+	}
+	return time.Time{}, false
+}
+
+// @time modifier reformats the timestamps and epochs of a value, or of the
+// elements of an array. The values that are not a time are kept. The arg is
+// the output layout, or an object with a "layout", the "in" layout or
+// layouts of the input, and a "tz" for the zone. The layout "unix" or
+// "unixmilli" outputs an epoch. The default layout is RFC3339Nano.
+//
+//	"2024-01-02 03:04:05" @time:"yyyy/MM/dd" -> "2024/01/02"
+//	1700000000 @time:{"layout":"yyyy-MM-dd HH:mm","tz":"UTC"} -> "2023-11-14 22:13"
+//	"02/01/2024" @time:{"in":"dd/MM/yyyy","layout":"unix","tz":"UTC"} -> 1704153600
+func modTime(json, arg string) string {
+	layout, layouts, loc := time.RFC3339Nano, TimeLayouts, time.Local
+	switch res := Parse(trim(arg)); res.Type {
+	case String:
+		layout = res.Str
+	case JSON:
+		res.ForEach(func(key, value Result) bool {
+			switch key.String() {
+			case "layout":
+				layout = value.String()
+			case "in":
+				layouts = nil
+				for _, v := range value.Array() {
+					layouts = append(layouts, v.String())
+				}
+			case "tz":
+				if l, err := time.LoadLocation(value.String()); err == nil {
+					loc = l
+				}
+			}
+			return true
+		})
+	}
+	format := func(value Result) []byte {
+		tm, ok := value.timeAuto(layouts, loc)
+		if !ok {
+			return []byte(value.Raw)
+		}
+		tm = tm.In(loc)
+		switch layout {
+		case "unix":
+			return strconv.AppendInt(nil, tm.Unix(), 10)
+		case "unixmilli":
+			return strconv.AppendInt(nil, tm.UnixMilli(), 10)
+		}
+		return AppendJSONString(nil, tm.Format(goLayout(layout)))
+	}
+	res := Parse(json)
+	if !res.IsArray() {
+		return bytesString(format(res))
+	}
+	out := []byte{'['}
+	res.ForEach(func(_, value Result) bool {
+		if len(out) > 1 {
+			out = append(out, ',')
+		}
+		out = append(out, format(value)...)
+		return true
+	})
+	out = append(out, ']')
+	return bytesString(out)
+}
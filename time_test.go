@@ -0,0 +1,81 @@
+package jj
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeLayout(t *testing.T) {
+	const out = "2006-01-02 15:04:05"
+	tests := []struct {
+		json    string
+		layouts []string
+		expect  string
+	}{
+		{`"2024-01-02 03:04:05"`, nil, "2024-01-02 03:04:05"},
+		{`"2024-01-02T03:04:05.123Z"`, nil, "2024-01-02 03:04:05"},
+		{`"2024/01/02"`, nil, "2024-01-02 00:00:00"},
+		{`"20240102030405"`, nil, "2024-01-02 03:04:05"},
+		{`"2024年01月02日 03时04分05秒"`, nil, "2024-01-02 03:04:05"},
+		{`"2024年1月2日"`, nil, "2024-01-02 00:00:00"},
+		{`"02/01/2024 03:04"`, []string{"dd/MM/yyyy HH:mm"}, "2024-01-02 03:04:00"},
+		{`20240102`, []string{"yyyyMMdd"}, "2024-01-02 00:00:00"},
+		{`"2024-01-02"`, []string{"yyyyMMdd"}, "0001-01-01 00:00:00"},
+		{`"nope"`, nil, "0001-01-01 00:00:00"},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.json).TimeLayout(tt.layouts...).Format(out); got != tt.expect {
+			t.Fatalf("json '%v': expected '%v', got '%v'", tt.json, tt.expect, got)
+		}
+	}
+}
+
+func TestTimeAuto(t *testing.T) {
+	tests := []struct {
+		json   string
+		expect int64 // nanos since the epoch
+	}{
+		{`1700000000`, 1700000000e9},
+		{`1700000000.5`, 1700000000.5e9},
+		{`-86400`, -86400e9},
+		{`1700000000123`, 1700000000123e6},
+		{`1700000000123456`, 1700000000123456e3},
+		{`1700000000123456789`, 1700000000123456789},
+		{`"1700000000123"`, 1700000000123e6},
+		{`"2023-11-14T22:13:20Z"`, 1700000000e9},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.json).TimeAuto().UnixNano(); got != tt.expect {
+			t.Fatalf("json '%v': expected '%v', got '%v'", tt.json, tt.expect, got)
+		}
+	}
+	assert(t, Parse(`true`).TimeAuto().IsZero())
+	assert(t, Parse(`"x1"`).TimeAuto().IsZero())
+	assert(t, Parse(`1e300`).TimeAuto().IsZero())
+	assert(t, Parse(`"2024-01-02 03:04:05"`).TimeAuto().Equal(
+		time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)))
+}
+
+func TestModTime(t *testing.T) {
+	json := `{"a":1700000000,"b":"2024-01-02 03:04:05","c":[1700000000123,"x",null],"d":"02/01/2024"}`
+	tests := []struct {
+		path   string
+		expect string
+	}{
+		{`a|@time:{"tz":"UTC"}`, `"2023-11-14T22:13:20Z"`},
+		{`a|@time:{"layout":"yyyy-MM-dd HH:mm","tz":"UTC"}`, `"2023-11-14 22:13"`},
+		{`b|@time:{"layout":"yyyy/MM/dd","tz":"UTC"}`, `"2024/01/02"`},
+		{`b|@time:{"layout":"unix","tz":"UTC"}`, `1704164645`},
+		{`c|@time:{"layout":"unixmilli","tz":"UTC"}`, `[1700000000123,"x",null]`},
+		{`c|@time:{"layout":"2006-01-02","tz":"UTC"}`, `["2023-11-14","x",null]`},
+		{`d|@time:{"in":"dd/MM/yyyy","layout":"unix","tz":"UTC"}`, `1704153600`},
+		{`d|@time:{"in":["yyyy","dd/MM/yyyy"],"layout":"yyyy-MM-dd","tz":"UTC"}`, `"2024-01-02"`},
+		{`d|@time:"yyyy"`, `"02/01/2024"`},
+		{`z|@time`, ``},
+	}
+	for _, tt := range tests {
+		if got := Get(json, tt.path).Raw; got != tt.expect {
+			t.Fatalf("path '%v': expected '%v', got '%v'", tt.path, tt.expect, got)
+		}
+	}
+}
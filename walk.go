@@ -0,0 +1,124 @@
+package jj
+
+import "strconv"
+
+// Walk calls iter for every leaf value of json with its GJSON path, like
+// "friends.0.nets.1", in the order of the document. The leaves are the
+// values that are not an object or an array, and the empty objects and
+// arrays. The keys are escaped, so that each path gets its value back with
+// Get. A json that is not an object or an array is a leaf with the empty
+// path, and an empty json has no leaves. Returning false stops the walk.
+//
+//	jj.Walk(`{"name":"Tom","nets":["ig","fb"]}`, func(path string, value jj.Result) bool {
+//		println(path, value.String()) // name Tom, nets.0 ig, nets.1 fb
+//		return true
+//	})
+func Walk(json string, iter func(path string, value Result) bool) {
+	w := pathWalker{iter: iter}
+	w.walk(Parse(json))
+}
+
+// PathsOptions are the options of the Paths function.
+type PathsOptions struct {
+	// Pointer lists RFC 6901 JSON Pointers, like "/friends/0/first",
+	// instead of the GJSON paths.
+	Pointer bool
+	// Collapse replaces the array indexes with '#', like "friends.#.first",
+	// which lists every path of the structure once.
+	Collapse bool
+}
+
+// PathType is a leaf path of a json document, with the type of its value.
+type PathType struct {
+	Path string
+	Type Type
+}
+
+// Paths lists the leaf paths of json, like Walk does, with the type of
+// their values. With the Collapse option a path is listed once for each
+// type of its values.
+//
+//	jj.Paths(`{"friends":[{"first":"Dale"},{"first":"Jane","age":44}]}`, jj.PathsOptions{Collapse: true})
+//	// friends.#.first String, friends.#.age Number
+func Paths(json string, options ...PathsOptions) []PathType {
+	var opts PathsOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	var paths []PathType
+	seen := make(map[PathType]bool)
+	w := pathWalker{pointer: opts.Pointer, collapse: opts.Collapse}
+	w.iter = func(path string, value Result) bool {
+		p := PathType{Path: path, Type: value.Type}
+		if !opts.Collapse || !seen[p] {
+			seen[p] = true
+			paths = append(paths, p)
+		}
+		return true
+	}
+	w.walk(Parse(json))
+	return paths
+}
+
+// pathWalker walks the leaves of a json, building their paths in a buffer
+// that is shared by all of them.
+type pathWalker struct {
+	pointer  bool
+	collapse bool
+	path     []byte
+	depth    int
+	iter     func(path string, value Result) bool
+}
+
+func (w *pathWalker) walk(value Result) bool {
+	if !value.Exists() {
+		return true
+	}
+	if !value.IsObject() && !value.IsArray() {
+		return w.iter(string(w.path), value)
+	}
+	n := len(w.path)
+	array := value.IsArray()
+	ok, leaf := true, true
+	var i int
+	w.depth++
+	value.ForEach(func(key, child Result) bool {
+		leaf = false
+		switch {
+		case !array:
+			w.append(key.Str)
+		case w.collapse:
+			w.appendRaw("#")
+		default:
+			w.appendRaw(strconv.Itoa(i))
+			i++
+		}
+		ok = w.walk(child)
+		w.path = w.path[:n]
+		return ok
+	})
+	w.depth--
+	if leaf {
+		return w.iter(string(w.path), value)
+	}
+	return ok
+}
+
+// append appends a key to the path.
+func (w *pathWalker) append(key string) {
+	if w.pointer {
+		w.path = appendPointerToken(w.path, key)
+	} else {
+		w.appendRaw(escapeComp(key))
+	}
+}
+
+// appendRaw appends a component that needs no escaping to the path.
+func (w *pathWalker) appendRaw(comp string) {
+	if w.pointer {
+		w.path = append(w.path, '/')
+	} else if w.depth > 1 {
+		w.path = append(w.path, '.')
+	}
+	w.path = append(w.path, comp...)
+}
@@ -0,0 +1,91 @@
+package jj
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	json := `{"name":{"first":"Tom"},"age":37,"friends":[{"nets":["ig","fb"]},{"nets":[]}],` +
+		`"fav.movie":"Deer Hunter","a*b?":{"#":1,"c|d":null},"":{"":true},"e":{}}`
+	var paths []string
+	Walk(json, func(path string, value Result) bool {
+		paths = append(paths, path)
+		got := Get(json, path)
+		if got.Raw != value.Raw || json[value.Index:value.Index+len(value.Raw)] != value.Raw {
+			t.Fatalf("path '%v': expected '%v', got '%v'", path, value.Raw, got.Raw)
+		}
+		return true
+	})
+	expect := []string{
+		`name.first`, `age`, `friends.0.nets.0`, `friends.0.nets.1`, `friends.1.nets`,
+		`fav\.movie`, `a\*b\?.\#`, `a\*b\?.c\|d`, `.`, `e`,
+	}
+	assert(t, reflect.DeepEqual(paths, expect))
+
+	paths = nil
+	Walk(json, func(path string, value Result) bool {
+		paths = append(paths, path)
+		return len(paths) < 3
+	})
+	assert(t, reflect.DeepEqual(paths, expect[:3]))
+
+	paths = nil
+	Walk(`"x"`, func(path string, value Result) bool {
+		paths = append(paths, path+"="+value.Str)
+		return true
+	})
+	assert(t, reflect.DeepEqual(paths, []string{"=x"}))
+}
+
+func TestPaths(t *testing.T) {
+	json := `{"friends":[{"first":"Dale","nets":["ig"]},{"first":"Jane","age":44,"nets":["fb",1]},{"first":null}],` +
+		`"a/b":{"c~d":1}}`
+	tests := []struct {
+		opts   PathsOptions
+		expect []PathType
+	}{
+		{PathsOptions{}, []PathType{
+			{"friends.0.first", String}, {"friends.0.nets.0", String},
+			{"friends.1.first", String}, {"friends.1.age", Number},
+			{"friends.1.nets.0", String}, {"friends.1.nets.1", Number},
+			{"friends.2.first", Null}, {`a\/b.c\~d`, Number},
+		}},
+		{PathsOptions{Collapse: true}, []PathType{
+			{"friends.#.first", String}, {"friends.#.nets.#", String},
+			{"friends.#.age", Number}, {"friends.#.nets.#", Number},
+			{"friends.#.first", Null}, {`a\/b.c\~d`, Number},
+		}},
+		{PathsOptions{Pointer: true}, []PathType{
+			{"/friends/0/first", String}, {"/friends/0/nets/0", String},
+			{"/friends/1/first", String}, {"/friends/1/age", Number},
+			{"/friends/1/nets/0", String}, {"/friends/1/nets/1", Number},
+			{"/friends/2/first", Null}, {"/a~1b/c~0d", Number},
+		}},
+		{PathsOptions{Pointer: true, Collapse: true}, []PathType{
+			{"/friends/#/first", String}, {"/friends/#/nets/#", String},
+			{"/friends/#/age", Number}, {"/friends/#/nets/#", Number},
+			{"/friends/#/first", Null}, {"/a~1b/c~0d", Number},
+		}},
+	}
+	for _, tt := range tests {
+		got := Paths(json, tt.opts)
+		if !reflect.DeepEqual(got, tt.expect) {
+			t.Fatalf("options '%+v': expected '%v', got '%v'", tt.opts, tt.expect, got)
+		}
+		if tt.opts.Collapse {
+			continue
+		}
+		for _, p := range got {
+			var res Result
+			if tt.opts.Pointer {
+				res = GetPointer(json, p.Path)
+			} else {
+				res = Get(json, p.Path)
+			}
+			assert(t, res.Exists() && res.Type == p.Type)
+		}
+	}
+	assert(t, len(Paths(`[]`)) == 1 && Paths(`[]`)[0].Path == "")
+	assert(t, Paths(``) == nil)
+}